@@ -0,0 +1,143 @@
+// Package certs serves a TLS certificate from files and reloads it when they change.
+//
+// A reloaded certificate is used for new connections, Connections already open keep the one they were made with.
+// A certificate that can't be loaded, e.g. while only one of the files has been replaced, is reported
+// and the one loaded before is kept.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// Reloader holds the certificate loaded from a pair of certificate and key files
+type Reloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+
+	// Modification times of the files when they were last checked, Only used by Watch
+	certMod, keyMod time.Time
+}
+
+// Load loads the certificate in certFile and it's private key in keyFile
+func Load(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+
+	var err error
+	r.certMod, r.keyMod, err = r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate from the files again, The certificate loaded before is kept if it fails
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	log.Infof("Loaded TLS certificate of %v, It expires at %s", leaf.DNSNames, leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// GetCertificate returns the certificate, It's meant for tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Watch reloads the certificate on SIGHUP and when either of the files is modified, Until ctx is done.
+// Files are checked for modifications every interval, They are only reloaded on SIGHUP if it's 0.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			if err := r.Reload(); err != nil {
+				log.Warnf("Error in reloading TLS certificate, Still using the previous one: %s", err)
+			}
+
+		case <-tick:
+			if !r.modified() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				log.Warnf("Error in reloading TLS certificate, Still using the previous one: %s", err)
+			}
+		}
+	}
+}
+
+// modified reports whether either of the files has been modified since it was last checked.
+// Files that fail to load are reported once, They are loaded again when they are modified again.
+func (r *Reloader) modified() bool {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		// Files are missing for a moment while they are replaced, They are checked again on the next tick
+		return false
+	}
+
+	if certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return false
+	}
+
+	r.certMod, r.keyMod = certMod, keyMod
+	return true
+}
+
+// modTimes returns modification times of the files, Symlinks are followed
+// So certificates mounted from Kubernetes secrets are reloaded too.
+func (r *Reloader) modTimes() (certMod, keyMod time.Time, err error) {
+	c, err := os.Stat(r.certFile)
+	if err != nil {
+		return certMod, keyMod, err
+	}
+
+	k, err := os.Stat(r.keyFile)
+	if err != nil {
+		return certMod, keyMod, err
+	}
+
+	return c.ModTime(), k.ModTime(), nil
+}
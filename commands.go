@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/common"
+	"github.com/ishanjain28/envelope-backend/db"
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// command is an operation run with the binary's first argument, e.g. envelope-backend ban <deviceid>.
+// Every command accepts the configuration flags too, So it runs against the same databases as the server.
+type command struct {
+	name string
+	// usage describes the positional arguments of the command
+	usage   string
+	summary string
+	// run returns an error when the command fails, The process exits with it after run has cleaned up
+	run func(fs *flag.FlagSet, args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "", "Serve the API, This is the default when no command is given", serve},
+		{"migrate", "", "Create tables and apply pending migrations", migrateCommand},
+		{"seed", "", "Generate fake posts, likes, comments and reports for load testing", seedCommand},
+		{"ban", "<deviceid>", "Ban a device, It can't register again and it's registration is removed", banCommand},
+		{"unban", "<deviceid>", "Lift the ban of a device", unbanCommand},
+		{"purge-ips", "", "Remove IP addresses of old posts", purgeIPsCommand},
+		{"export", "", "Export posts with their comments as JSON lines", exportCommand},
+		{"recount", "", "Repair drift in engagement counters of posts", recountCommand},
+		{"check-plans", "", "Fail if any feed query is planned with a sequential scan", checkPlansCommand},
+	}
+}
+
+// runCommand runs the command named cmd with args, It exits with 1 when the command fails.
+// It prints usage when cmd is unknown.
+func runCommand(cmd string, args []string) {
+	for _, c := range commands() {
+		if c.name != cmd {
+			continue
+		}
+
+		fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\n%s\n\nFlags:\n", os.Args[0], c.name, c.usage, c.summary)
+			fs.PrintDefaults()
+		}
+
+		if err := c.run(fs, args); err != nil {
+			log.Fatalf("%s", err)
+		}
+		return
+	}
+
+	usage()
+	if cmd != "help" {
+		fmt.Fprintf(os.Stderr, "\nUnknown command %s\n", cmd)
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for flags of a command\n", os.Args[0])
+}
+
+// positional returns the n positional arguments left in fs after parsing, It exits with usage when there are more or less
+func positional(fs *flag.FlagSet, n int) []string {
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Args()
+}
+
+func migrateCommand(fs *flag.FlagSet, args []string) error {
+	status := fs.Bool("status", false, "only report the version of the schema, Without applying migrations")
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	open := db.Open
+	if *status {
+		open = db.Connect
+	}
+
+	dbs, err := open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	current, latest, err := dbs.MigrationStatus(context.Background())
+	if err != nil {
+		return fmt.Errorf("error in reading version of the schema: %s", err)
+	}
+
+	log.Infof("Schema is at version %d, Latest version is %d", current, latest)
+	if current < latest {
+		return fmt.Errorf("%d migrations are pending", latest-current)
+	}
+	return nil
+}
+
+func seedCommand(fs *flag.FlagSet, args []string) error {
+	o := &db.SeedOptions{}
+	posts := fs.Int("posts", 10000, "number of posts to generate")
+	fs.IntVar(&o.Devices, "devices", 5000, "number of devices that create posts, likes and comments")
+	fs.DurationVar(&o.Period, "period", 30*24*time.Hour, "posts are spread over this period before now")
+	fs.Float64Var(&o.Likes, "likes", 5, "average number of likes on a post")
+	fs.Float64Var(&o.Comments, "comments", 2, "average number of comments on a post")
+	fs.Float64Var(&o.ReportRatio, "report-ratio", 0.01, "fraction of posts that are reported")
+	fs.Int64Var(&o.Seed, "random-seed", 1, "seed of the random generator, The same seed generates the same data")
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	if *posts <= 0 || o.Devices <= 0 {
+		return fmt.Errorf("-posts and -devices must be more than 0")
+	}
+	o.Posts = *posts
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	err = dbs.Seed(context.Background(), *o)
+	if err != nil {
+		return fmt.Errorf("error in seeding: %s", err)
+	}
+	return nil
+}
+
+func banCommand(fs *flag.FlagSet, args []string) error {
+	reason := fs.String("reason", "", "why the device is banned, Required")
+	deletePosts := fs.Bool("delete-posts", false, "delete every post of the device too")
+	cfg := loadConfig(fs, args)
+	deviceid := positional(fs, 1)[0]
+
+	if *reason == "" {
+		return fmt.Errorf("-reason is required")
+	}
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	deleted, err := dbs.Ban(context.Background(), deviceid, *reason, *deletePosts)
+	if err != nil {
+		return fmt.Errorf("error in banning %s: %s", common.DeviceHash(deviceid), err)
+	}
+
+	log.Infof("Banned %s", common.DeviceHash(deviceid))
+	if *deletePosts {
+		log.Infof("Deleted %d posts of %s", len(deleted), common.DeviceHash(deviceid))
+	}
+	return nil
+}
+
+func unbanCommand(fs *flag.FlagSet, args []string) error {
+	cfg := loadConfig(fs, args)
+	deviceid := positional(fs, 1)[0]
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	banned, err := dbs.Unban(context.Background(), deviceid)
+	if err != nil {
+		return fmt.Errorf("error in unbanning %s: %s", common.DeviceHash(deviceid), err)
+	}
+
+	if !banned {
+		log.Warnf("%s was not banned", common.DeviceHash(deviceid))
+		return nil
+	}
+	log.Infof("Unbanned %s", common.DeviceHash(deviceid))
+	return nil
+}
+
+func purgeIPsCommand(fs *flag.FlagSet, args []string) error {
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "remove IP addresses of posts older than this")
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	n, err := dbs.PurgeIPs(context.Background(), time.Now().Add(-*olderThan))
+	if err != nil {
+		return fmt.Errorf("error in purging IP addresses: %s", err)
+	}
+
+	log.Infof("Removed IP addresses of %d posts older than %s", n, *olderThan)
+	return nil
+}
+
+func exportCommand(fs *flag.FlagSet, args []string) error {
+	out := fs.String("out", "-", "file posts are written to, - writes them to stdout")
+	device := fs.String("device", "", "export only posts of this deviceid")
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	w := os.Stdout
+	if *out == "-" {
+		// Logs are written to stdout too, Only errors are logged so they don't get mixed in the export
+		log.SetLevel(log.LevelError)
+	} else {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		// Closed below to catch errors in writing, This only closes it when exporting fails
+		defer f.Close()
+		w = f
+	}
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	bw := bufio.NewWriter(w)
+	n, err := dbs.Export(context.Background(), bw, *device)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil && w != os.Stdout {
+		err = w.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error in exporting after %d posts: %s", n, err)
+	}
+
+	log.Infof("Exported %d posts to %s", n, *out)
+	return nil
+}
+
+func recountCommand(fs *flag.FlagSet, args []string) error {
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	n, err := dbs.Recount(context.Background())
+	if err != nil {
+		return fmt.Errorf("error in recounting: %s", err)
+	}
+
+	log.Infof("Repaired counters of %d posts", n)
+	return nil
+}
+
+func checkPlansCommand(fs *flag.FlagSet, args []string) error {
+	cfg := loadConfig(fs, args)
+	positional(fs, 0)
+
+	dbs, err := db.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbs.Close()
+
+	err = dbs.CheckQueryPlans(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("All feed queries use indexes")
+	return nil
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -37,3 +39,10 @@ func GetIPAddr(r *http.Request) string {
 		return headerIP
 	}
 }
+
+// DeviceHash returns a short hash of deviceid, That identifies the device in logs without revealing it's deviceid.
+// Request logs and the admin commands use it, So a device can be followed through both.
+func DeviceHash(deviceid string) string {
+	h := sha256.Sum256([]byte(deviceid))
+	return hex.EncodeToString(h[:6])
+}
@@ -0,0 +1,519 @@
+// Package config loads configuration of the application.
+//
+// Every setting has a default that can be overridden by a YAML or TOML file, Then by environment variables
+// and then by command line flags. The file is read from -config or $CONFIG_FILE, It's format is decided by
+// it's extension. Environment variables and flags of each setting are named in the tags of it's field,
+// See config.example.yaml for all the settings.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ishanjain28/envelope-backend/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the application.
+// Tags of a setting name it's key in the file, It's environment variable and it's flag.
+// Settings tagged secret are redacted when the configuration is printed.
+type Config struct {
+	Server     Server     `yaml:"server" toml:"server"`
+	TLS        TLS        `yaml:"tls" toml:"tls"`
+	API        API        `yaml:"api" toml:"api"`
+	RateLimit  RateLimit  `yaml:"rate_limit" toml:"rate_limit"`
+	Moderation Moderation `yaml:"moderation" toml:"moderation"`
+	Postgres   Postgres   `yaml:"postgres" toml:"postgres"`
+	Redis      Redis      `yaml:"redis" toml:"redis"`
+	Health     Health     `yaml:"health" toml:"health"`
+	Log        Log        `yaml:"log" toml:"log"`
+	Tracing    Tracing    `yaml:"tracing" toml:"tracing"`
+}
+
+// Server configures the HTTP server
+type Server struct {
+	Port              int           `yaml:"port" toml:"port" env:"PORT" flag:"port" usage:"port to listen on"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"time a client has to send headers of a request"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT" flag:"read-timeout" usage:"time a client has to send a request including it's body"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT" flag:"write-timeout" usage:"time a response has to be written in, Streams extend it on every write"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT" flag:"idle-timeout" usage:"time a keep-alive connection is kept open without requests"`
+	DrainDelay        time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"DRAIN_DELAY" flag:"drain-delay" usage:"time requests are still accepted after reporting not ready on shutdown"`
+	MetricsAddress    string        `yaml:"metrics_address" toml:"metrics_address" env:"METRICS_ADDRESS" flag:"metrics-address" usage:"host:port metrics are served on at /metrics, Not served if empty"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time shutdown has to finish in, Including drain delay"`
+}
+
+// TLS configures serving HTTPS on the port of Server, It's enabled when a certificate is set.
+// HTTP/2 is negotiated along with it.
+type TLS struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file" env:"TLS_CERT_FILE" flag:"tls-cert-file" usage:"PEM file of the certificate and it's chain, Serves HTTPS when set"`
+	KeyFile  string `yaml:"key_file" toml:"key_file" env:"TLS_KEY_FILE" flag:"tls-key-file" usage:"PEM file of the private key of the certificate"`
+	// The files are reloaded on SIGHUP too
+	WatchInterval time.Duration `yaml:"watch_interval" toml:"watch_interval" env:"TLS_WATCH_INTERVAL" flag:"tls-watch-interval" usage:"time between checks for changes of the certificate files, Disabled if 0"`
+	RedirectPort  int           `yaml:"redirect_port" toml:"redirect_port" env:"TLS_REDIRECT_PORT" flag:"tls-redirect-port" usage:"port plain HTTP is served on and redirected to HTTPS from, Disabled if 0"`
+	// Strict-Transport-Security is sent with every response when it's max age is set, Behind a proxy that terminates TLS too
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age" env:"HSTS_MAX_AGE" flag:"hsts-max-age" usage:"max-age of Strict-Transport-Security, Not sent if 0"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains" env:"HSTS_INCLUDE_SUBDOMAINS" flag:"hsts-include-subdomains" usage:"apply Strict-Transport-Security to subdomains too"`
+	HSTSPreload           bool          `yaml:"hsts_preload" toml:"hsts_preload" env:"HSTS_PRELOAD" flag:"hsts-preload" usage:"allow the domain to be preloaded in browsers as HTTPS only"`
+}
+
+// Enabled reports whether HTTPS is served
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// API configures handling of requests
+type API struct {
+	RequestTimeout  time.Duration `yaml:"request_timeout" toml:"request_timeout" env:"REQUEST_TIMEOUT" flag:"request-timeout" usage:"timeout of routes that don't set their own"`
+	MaxBodyBytes    int64         `yaml:"max_body_bytes" toml:"max_body_bytes" env:"MAX_BODY_BYTES" flag:"max-body-bytes" usage:"maximum size of a request body"`
+	TrustedProxies  []string      `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"IPs and CIDRs of proxies trusted to set X-Request-ID, Comma separated"`
+	ErrorReportFile string        `yaml:"error_report_file" toml:"error_report_file" env:"ERROR_REPORT_FILE" flag:"error-report-file" usage:"file errors are reported to as JSON lines, stdout if empty"`
+	// Regions devices can register from, As named by ipapi.co. Devices from any region can register when it's empty
+	Regions []string `yaml:"regions" toml:"regions" env:"REGIONS" flag:"regions" usage:"regions devices can register from, Comma separated. Any region if empty"`
+}
+
+// RateLimit configures how many posts, comments and reports a device can create in a window.
+// Counts are kept in Redis, So they are shared by every instance. A limit of 0 is unlimited
+type RateLimit struct {
+	Window   time.Duration `yaml:"window" toml:"window" env:"RATE_LIMIT_WINDOW" flag:"rate-limit-window" usage:"time a device's counts are kept for, Counting starts with the first request of the window"`
+	Posts    int           `yaml:"posts" toml:"posts" env:"RATE_LIMIT_POSTS" flag:"rate-limit-posts" usage:"posts a device can submit per window, Unlimited if 0"`
+	Comments int           `yaml:"comments" toml:"comments" env:"RATE_LIMIT_COMMENTS" flag:"rate-limit-comments" usage:"comments a device can submit per window, Unlimited if 0"`
+	Reports  int           `yaml:"reports" toml:"reports" env:"RATE_LIMIT_REPORTS" flag:"rate-limit-reports" usage:"reports a device can submit per window, Unlimited if 0"`
+}
+
+// Moderation configures what happens to posts as they are reported
+type Moderation struct {
+	// Posts are flagged with a warning in logs, So moderators can review them before they are hidden
+	FlagReports int `yaml:"flag_reports" toml:"flag_reports" env:"MODERATION_FLAG_REPORTS" flag:"moderation-flag-reports" usage:"reports after which a post is logged for review, Never if 0"`
+	HideReports int `yaml:"hide_reports" toml:"hide_reports" env:"MODERATION_HIDE_REPORTS" flag:"moderation-hide-reports" usage:"reports after which a post is hidden from feeds, Never if 0"`
+}
+
+// Postgres configures the connection to Postgresql and it's pool
+type Postgres struct {
+	URL             string        `yaml:"url" toml:"url" env:"DATABASE_URL" flag:"postgres-url" usage:"Postgresql connection string" secret:"true"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" flag:"postgres-max-open-conns" usage:"maximum number of open connections, Unlimited if 0"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" flag:"postgres-max-idle-conns" usage:"maximum number of idle connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" flag:"postgres-conn-max-lifetime" usage:"time after which a connection is replaced, Never if 0"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" flag:"postgres-conn-max-idle-time" usage:"time after which an idle connection is closed, Never if 0"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"POSTGRES_CONNECT_TIMEOUT" flag:"postgres-connect-timeout" usage:"time connecting at startup is retried for"`
+}
+
+// Redis configures the connection to Redis and it's pool
+type Redis struct {
+	URL            string        `yaml:"url" toml:"url" env:"REDISTOGO_URL" flag:"redis-url" usage:"Redis URL, e.g. redis://localhost:6379/0" secret:"true"`
+	PoolSize       int           `yaml:"pool_size" toml:"pool_size" env:"REDIS_POOL_SIZE" flag:"redis-pool-size" usage:"maximum number of connections, 10 per CPU if 0"`
+	MinIdleConns   int           `yaml:"min_idle_conns" toml:"min_idle_conns" env:"REDIS_MIN_IDLE_CONNS" flag:"redis-min-idle-conns" usage:"number of idle connections kept open"`
+	PoolTimeout    time.Duration `yaml:"pool_timeout" toml:"pool_timeout" env:"REDIS_POOL_TIMEOUT" flag:"redis-pool-timeout" usage:"time a command waits for a connection when all are busy"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"REDIS_IDLE_TIMEOUT" flag:"redis-idle-timeout" usage:"time after which an idle connection is closed"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"REDIS_CONNECT_TIMEOUT" flag:"redis-connect-timeout" usage:"time connecting at startup is retried for"`
+}
+
+// Health configures monitoring of the dependencies
+type Health struct {
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"HEALTH_CHECK_INTERVAL" flag:"health-check-interval" usage:"time between checks of Postgres, Redis and migrations, Disabled if 0"`
+}
+
+// Log configures logging, See package log
+type Log struct {
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum level of logged lines: debug, info, warn or error"`
+	// json or text, When it's empty lines are JSON unless stdout is a terminal
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"format of logged lines: json or text, Decided by stdout if empty"`
+}
+
+// Tracing configures exporting of spans, See package tracing
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"TRACE_EXPORTER" flag:"trace-exporter" usage:"where spans are exported: otlp, stdout, file or none"`
+	File        string  `yaml:"file" toml:"file" env:"TRACE_FILE" flag:"trace-file" usage:"file spans are written to by the file exporter"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACE_SAMPLE_RATIO" flag:"trace-sample-ratio" usage:"fraction of new traces that are sampled"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+// URLs of the databases have no defaults, They must be set.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:              5000,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MetricsAddress:    ":9090",
+			ShutdownTimeout:   30 * time.Second,
+		},
+		TLS: TLS{
+			WatchInterval: 10 * time.Second,
+		},
+		API: API{
+			RequestTimeout: 5 * time.Second,
+			MaxBodyBytes:   64 << 10,
+		},
+		RateLimit: RateLimit{
+			Window:   time.Hour,
+			Posts:    30,
+			Comments: 120,
+			Reports:  30,
+		},
+		Moderation: Moderation{
+			FlagReports: 3,
+			HideReports: 10,
+		},
+		Postgres: Postgres{
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+		},
+		Redis: Redis{
+			PoolTimeout:    4 * time.Second,
+			IdleTimeout:    5 * time.Minute,
+			ConnectTimeout: 30 * time.Second,
+		},
+		Health: Health{
+			Interval: 15 * time.Second,
+		},
+		Log: Log{
+			Level: "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load loads the configuration and validates it.
+// Flags of every setting and -config are added to fs and args are parsed with it,
+// So commands can add flags of their own to fs before calling Load.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := Default()
+	settings := c.settings()
+
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML file to read configuration from")
+
+	// Flags are applied after the file and environment, So their values are kept till then
+	flags := map[*setting]string{}
+	for _, s := range settings {
+		s := s
+		set := func(v string) error {
+			if err := s.set(v); err != nil {
+				return err
+			}
+			flags[s] = v
+			return nil
+		}
+
+		// Switches can be passed without a value, e.g. -hsts-preload
+		if s.v.Kind() == reflect.Bool {
+			fs.BoolFunc(s.flag, s.usage, set)
+		} else {
+			fs.Func(s.flag, s.usage, set)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := c.readFile(*file); err != nil {
+			return nil, fmt.Errorf("error in reading configuration file %s: %s", *file, err)
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("invalid $%s: %s", s.env, err)
+			}
+		}
+	}
+
+	for s, v := range flags {
+		s.set(v)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile overrides c with the settings in file, Settings missing in it are left alone.
+// Unknown keys are an error, They are usually typos.
+func (c *Config) readFile(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+
+		err := dec.Decode(c)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+
+	case ".toml":
+		md, err := toml.Decode(string(b), c)
+		if err != nil {
+			return err
+		}
+
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown key %s", undecoded[0])
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown format, It must be .yaml, .yml or .toml")
+}
+
+// Validate checks every setting and returns an error describing all the invalid ones
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(key, env, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s ($%s) %s", key, env, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		add("server.port", "PORT", "must be between 1 and 65535")
+	}
+
+	if a := c.Server.MetricsAddress; a != "" {
+		_, port, err := net.SplitHostPort(a)
+		if p, perr := strconv.Atoi(port); err != nil || perr != nil || p <= 0 || p > 65535 {
+			add("server.metrics_address", "METRICS_ADDRESS", "must be a host:port, e.g. :9090 or 10.0.0.5:9090")
+		} else if p == c.Server.Port || p == c.TLS.RedirectPort {
+			add("server.metrics_address", "METRICS_ADDRESS", "must be on a port other than server.port and tls.redirect_port")
+		}
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		add("tls.key_file", "TLS_KEY_FILE", "and tls.cert_file ($TLS_CERT_FILE) must be set together")
+	}
+	if p := c.TLS.RedirectPort; p != 0 {
+		switch {
+		case !c.TLS.Enabled():
+			add("tls.redirect_port", "TLS_REDIRECT_PORT", "needs tls.cert_file to be set")
+		case p < 0 || p > 65535:
+			add("tls.redirect_port", "TLS_REDIRECT_PORT", "must be between 1 and 65535")
+		case p == c.Server.Port:
+			add("tls.redirect_port", "TLS_REDIRECT_PORT", "must be different from server.port")
+		}
+	}
+	// Requirements of https://hstspreload.org
+	if c.TLS.HSTSPreload && (!c.TLS.HSTSIncludeSubdomains || c.TLS.HSTSMaxAge < 365*24*time.Hour) {
+		add("tls.hsts_preload", "HSTS_PRELOAD", "needs tls.hsts_include_subdomains and tls.hsts_max_age of at least a year (8760h)")
+	}
+
+	// Durations, Sizes and counts of connections are never negative
+	for _, s := range c.settings() {
+		if (s.v.Kind() == reflect.Int || s.v.Kind() == reflect.Int64) && s.v.Int() < 0 {
+			add(s.key, s.env, "can't be negative")
+		}
+	}
+	for key, d := range map[string]time.Duration{
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+		"api.request_timeout":        c.API.RequestTimeout,
+		"postgres.connect_timeout":   c.Postgres.ConnectTimeout,
+		"redis.connect_timeout":      c.Redis.ConnectTimeout,
+	} {
+		if d == 0 {
+			add(key, c.env(key), "must be set")
+		}
+	}
+
+	if c.API.MaxBodyBytes == 0 {
+		add("api.max_body_bytes", "MAX_BODY_BYTES", "can't be 0")
+	}
+
+	for _, p := range c.API.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			add("api.trusted_proxies", "TRUSTED_PROXIES", "has %s that is not an IP or CIDR", p)
+		}
+	}
+
+	limited := c.RateLimit.Posts > 0 || c.RateLimit.Comments > 0 || c.RateLimit.Reports > 0
+	if limited && c.RateLimit.Window < time.Second {
+		add("rate_limit.window", "RATE_LIMIT_WINDOW", "must be at least 1s when a limit is set")
+	}
+
+	if f, h := c.Moderation.FlagReports, c.Moderation.HideReports; f > 0 && h > 0 && f >= h {
+		add("moderation.flag_reports", "MODERATION_FLAG_REPORTS", "must be less than moderation.hide_reports ($MODERATION_HIDE_REPORTS), Posts are hidden before they can be reviewed")
+	}
+
+	if c.Postgres.URL == "" {
+		add("postgres.url", "DATABASE_URL", "must be set")
+	}
+
+	if c.Redis.URL == "" {
+		add("redis.url", "REDISTOGO_URL", "must be set")
+	} else if u, err := url.Parse(c.Redis.URL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+		add("redis.url", "REDISTOGO_URL", "must be a redis:// or rediss:// URL")
+	}
+
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		add("log.level", "LOG_LEVEL", "must be debug, info, warn or error")
+	}
+	if f := c.Log.Format; f != "" && f != "json" && f != "text" {
+		add("log.format", "LOG_FORMAT", "must be json or text")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "none", "otlp", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			add("tracing.file", "TRACE_FILE", "must be set when exporter is file")
+		}
+	default:
+		add("tracing.exporter", "TRACE_EXPORTER", "must be otlp, stdout, file or none")
+	}
+	if r := c.Tracing.SampleRatio; r < 0 || r > 1 {
+		add("tracing.sample_ratio", "TRACE_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML, Secrets are redacted so the output can be shared and logged
+func (c *Config) Print(w io.Writer) error {
+	r := *c
+	for _, s := range r.settings() {
+		if s.secret && s.v.String() != "" {
+			s.v.SetString(redact(s.v.String()))
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(r)
+}
+
+// redact hides the password of a URL, Anything else that is not a URL is hidden entirely
+func redact(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(u.RawQuery, "password") {
+		return "REDACTED"
+	}
+
+	return u.Redacted()
+}
+
+// setting is a single field of Config
+type setting struct {
+	// Key in the file, e.g. postgres.url
+	key    string
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	v      reflect.Value
+}
+
+// settings returns every setting of c, Their values can be set through them
+func (c *Config) settings() []*setting {
+	var settings []*setting
+
+	cv := reflect.ValueOf(c).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		section, sv := cv.Type().Field(i), cv.Field(i)
+
+		for j := 0; j < sv.NumField(); j++ {
+			f := sv.Type().Field(j)
+			settings = append(settings, &setting{
+				key:    section.Tag.Get("yaml") + "." + f.Tag.Get("yaml"),
+				env:    f.Tag.Get("env"),
+				flag:   f.Tag.Get("flag"),
+				usage:  f.Tag.Get("usage"),
+				secret: f.Tag.Get("secret") == "true",
+				v:      sv.Field(j),
+			})
+		}
+	}
+
+	return settings
+}
+
+// env returns the environment variable of setting key
+func (c *Config) env(key string) string {
+	for _, s := range c.settings() {
+		if s.key == key {
+			return s.env
+		}
+	}
+	return ""
+}
+
+// set parses v and sets it as the value of s. Lists are comma separated
+func (s *setting) set(v string) error {
+	if _, ok := s.v.Interface().(time.Duration); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		s.v.SetInt(int64(d))
+		return nil
+	}
+
+	switch s.v.Kind() {
+	case reflect.String:
+		s.v.SetString(v)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		s.v.SetBool(b)
+
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		s.v.SetInt(n)
+
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		s.v.SetFloat(f)
+
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.v.Set(reflect.ValueOf(list))
+
+	default:
+		return fmt.Errorf("unsupported type %s of %s", s.v.Type(), s.key)
+	}
+
+	return nil
+}
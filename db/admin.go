@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ishanjain28/envelope-backend/log"
+	"github.com/ishanjain28/envelope-backend/stream"
+)
+
+// Operations used by the administrative commands, So operators never have to write SQL against production.
+
+// MigrationStatus returns the version of the schema and the latest version known to this binary.
+// The version is 0 when no migration has ever been applied.
+func (d *DB) MigrationStatus(ctx context.Context) (current, latest int, err error) {
+	latest = migrations[len(migrations)-1].version
+
+	var exists bool
+	err = d.queryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return 0, latest, err
+	}
+
+	err = d.queryRowContext(ctx, "SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return current, latest, nil
+}
+
+// Ban bans deviceid with reason and removes it's registration, So the device can neither use the API nor register again.
+// Posts of the device are deleted too when deletePosts is set, It returns the IDs of the posts deleted.
+// A post.deleted event is published for every deleted post, So connected clients remove it from their feeds.
+func (d *DB) Ban(ctx context.Context, deviceid, reason string, deletePosts bool) ([]int, error) {
+	query := "INSERT INTO banned_devices(deviceid, reason) VALUES ($1, $2) ON CONFLICT (deviceid) DO UPDATE SET reason = EXCLUDED.reason"
+
+	_, err := d.execContext(ctx, query, deviceid, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		return c.Del(ctx, deviceid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !deletePosts {
+		return nil, nil
+	}
+
+	// Likes, Comments and Reports of the posts are deleted along with them
+	rows, err := d.queryContext(ctx, "DELETE FROM posts WHERE deviceid = $1 RETURNING postid", deviceid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deleted := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Same path as events published by the API, Failing to publish is logged since the posts are already deleted
+	hub := stream.NewHub(d)
+	for _, id := range deleted {
+		err := hub.Publish(ctx, &stream.Event{Type: stream.PostDeleted, PostID: id})
+		if err != nil {
+			log.FromContext(ctx).With(log.Fields{"postid": id}).Warnf("error in publishing %s event: %s", stream.PostDeleted, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// Unban lifts the ban of deviceid, It reports whether the device was banned
+func (d *DB) Unban(ctx context.Context, deviceid string) (bool, error) {
+	res, err := d.execContext(ctx, "DELETE FROM banned_devices WHERE deviceid = $1", deviceid)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// IsBanned reports whether deviceid has been banned
+func (d *DB) IsBanned(ctx context.Context, deviceid string) (bool, error) {
+	var banned bool
+	err := d.queryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM banned_devices WHERE deviceid = $1)", deviceid).Scan(&banned)
+	return banned, err
+}
+
+// PurgeIPs removes IP addresses of posts created before cutoff and returns the number of posts purged.
+// IP addresses are only needed while a post is fresh, e.g. to investigate abuse.
+func (d *DB) PurgeIPs(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := d.execContext(ctx, "UPDATE posts SET ipaddr = '' WHERE ipaddr <> '' AND timestamp < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// ExportedPost is a post written by Export, Along with it's comments.
+// IP addresses are never exported.
+type ExportedPost struct {
+	PostID        int               `json:"postid"`
+	DeviceID      string            `json:"deviceid"`
+	Text          string            `json:"post"`
+	CreatedAt     time.Time         `json:"created_at"`
+	LikesCount    int               `json:"likes_count"`
+	CommentsCount int               `json:"comments_count"`
+	ReportsCount  int               `json:"reports_count"`
+	Comments      []ExportedComment `json:"comments"`
+}
+
+// ExportedComment is a comment on an ExportedPost
+type ExportedComment struct {
+	CommentID int       `json:"commentid"`
+	DeviceID  string    `json:"deviceid"`
+	Text      string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportQuery selects every post with it's comments aggregated as JSON, Oldest post first.
+// Only posts of device $1 are selected when it's not empty.
+const exportQuery = `SELECT p.postid, p.deviceid, p.post, p.timestamp, p.likes_count, p.comments_count, p.reports_count,
+	coalesce((SELECT json_agg(json_build_object('commentid', c.commentid, 'deviceid', c.deviceid, 'comment', c.comment, 'created_at', c.timestamp) ORDER BY c.timestamp, c.commentid)
+		FROM comments c WHERE c.postid = p.postid), '[]')
+	FROM posts p WHERE $1 = '' OR p.deviceid = $1 ORDER BY p.postid`
+
+// Export writes every post to w as a line of JSON, Or only the posts of deviceid when it's not empty.
+// It returns the number of posts written.
+func (d *DB) Export(ctx context.Context, w io.Writer, deviceid string) (int, error) {
+	rows, err := d.queryContext(ctx, exportQuery, deviceid)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+
+	n := 0
+	for rows.Next() {
+		p := ExportedPost{}
+		var comments []byte
+
+		err := rows.Scan(&p.PostID, &p.DeviceID, &p.Text, &p.CreatedAt, &p.LikesCount, &p.CommentsCount, &p.ReportsCount, &comments)
+		if err != nil {
+			return n, err
+		}
+
+		if err := json.Unmarshal(comments, &p.Comments); err != nil {
+			return n, err
+		}
+
+		if err := enc.Encode(p); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
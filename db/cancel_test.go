@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TestCancelledQueryStops cancels the context of a running query and checks that Postgres stopped running it,
+// Not only that the caller stopped waiting for it.
+func TestCancelledQueryStops(t *testing.T) {
+	d := newTestDB(t)
+
+	// The comment tells the query apart from the others in pg_stat_activity
+	const query = "SELECT pg_sleep(30) /* TestCancelledQueryStops */"
+
+	running := func() int {
+		var n int
+		err := d.Pq.QueryRow(
+			"SELECT count(*) FROM pg_stat_activity WHERE datname = current_database() AND state = 'active' AND query = $1",
+			query,
+		).Scan(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.execContext(ctx, query)
+		done <- err
+	}()
+
+	waitFor(t, "query to start", func() bool { return running() == 1 })
+	cancel()
+	waitForError(t, done)
+	waitFor(t, "query to stop", func() bool { return running() == 0 })
+}
+
+// TestCancelledRedisCommandStops cancels the context of a BLPOP blocked on an empty list and checks that Redis
+// dropped it. An element pushed afterwards stays in the list, A BLPOP that was still blocked would have popped it.
+func TestCancelledRedisCommandStops(t *testing.T) {
+	d := newTestRedis(t)
+
+	key := fmt.Sprintf("envelope:test:blpop:%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		d.Redis.Del(context.Background(), key)
+	})
+
+	// Number of clients blocked on key, CLIENT LIST shows the command each client is running
+	blocked := func() int {
+		list, err := d.Redis.ClientList(context.Background()).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(list, "cmd=blpop")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+			return c.BLPop(ctx, 0, key)
+		})
+	}()
+
+	waitFor(t, "BLPOP to block", func() bool { return blocked() == 1 })
+	cancel()
+	waitForError(t, done)
+	waitFor(t, "BLPOP to be dropped", func() bool { return blocked() == 0 })
+
+	if err := d.Redis.RPush(context.Background(), key, "element").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.Redis.LLen(context.Background(), key).Val(); n != 1 {
+		t.Fatalf("list has %d elements after the cancelled BLPOP, want 1", n)
+	}
+}
+
+// waitFor waits up to 5s for cond to become true
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForError waits up to 5s for a cancelled call to return an error on done
+func waitForError(t *testing.T, done <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("cancelled call returned no error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled call is still being waited for after 5s")
+	}
+}
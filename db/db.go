@@ -3,12 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"os"
+	"math"
+	"sync"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/ishanjain28/envelope-backend/config"
 	"github.com/ishanjain28/envelope-backend/log"
 	"github.com/lib/pq"
 )
@@ -16,110 +17,205 @@ import (
 type DB struct {
 	Pq    *sql.DB
 	Redis *redis.Client
+
+	// hideReports is the number of reports after which posts are left out of feeds, Posts are never hidden if it's 0
+	hideReports int
+
+	// stopMonitor stops monitoring of health, It's closed by Close
+	stopMonitor chan struct{}
+	closeOnce   sync.Once
 }
 
 // IDB interface defines all the database operations used by the application.
 type IDB interface {
 	FetchNPosts(ctx context.Context, n int) ([]*Post, error)
 	FetchPostsFromID(ctx context.Context, id, limit int, prop string) ([]*Post, error)
-	LikePost(ctx context.Context, postid, deviceid string) error
-	Report(ctx context.Context, postid, deviceid, reason string) error
+	FetchPost(ctx context.Context, postid string) (*Post, error)
+	LikePost(ctx context.Context, postid, deviceid string) (int, error)
+	Report(ctx context.Context, postid, deviceid, reason string) (int, error)
 	SubmitPost(ctx context.Context, p *Post) error
-	//Comment(ctx context.Context, postid string, comment *Comment) error
-	//FetchPostComments(ctx context.Context, postid string) ([]*Comment, error)
+	EditPost(ctx context.Context, postid, deviceid, text string) (*Post, error)
+	// Recount repairs drift in engagement counters of posts and returns the number of posts that were fixed
+	Recount(ctx context.Context) (int64, error)
+	Comment(ctx context.Context, postid string, comment *Comment) error
+	FetchPostComments(ctx context.Context, postid string) ([]*Comment, error)
 
 	// Authentication related endpoints
 	VerifyDeviceID(ctx context.Context, deviceid string) (string, error)
 	RegisterDeviceID(ctx context.Context, deviceid, hash string, t time.Duration) error
+	// IsBanned reports whether deviceid has been banned, Banned devices can't register
+	IsBanned(ctx context.Context, deviceid string) (bool, error)
+	// CountRequest counts a request of deviceid to do action and returns the number of them made in the current window
+	CountRequest(ctx context.Context, deviceid, action string, window time.Duration) (int64, error)
+
+	// Events pushed to clients connected to the stream, These are delivered to every instance of the application
+	PublishEvent(ctx context.Context, payload []byte) error
+	SubscribeEvents() (<-chan []byte, func() error)
+	AppendEvent(ctx context.Context, payload []byte) (string, error)
+	EventsAfter(ctx context.Context, id string, fn func(id string, payload []byte) error) error
+
+	// Health checks the dependencies of the application, It's used by readiness probes
+	Health(ctx context.Context) []HealthCheck
+	// Close closes all the connections, It's called when the application shuts down
+	Close() error
 }
 
-var (
-	postgresAddr = os.Getenv("DATABASE_URL")
-	redisAddr    = os.Getenv("REDISTOGO_URL")
+// Init connects to Postgresql and Redis and returns an IDB to be used by the application.
+// Health of both is monitored in background until the IDB is closed, See monitor.
+func Init(c *config.Config) (IDB, error) {
+	db, err := Open(c)
+	if err != nil {
+		return nil, err
+	}
 
-	ErrInvalidPostID = "INVALID_POST_ID"
-	ErrAlreadyLiked  = "ALREADY_LIKED"
-)
+	if c.Health.Interval > 0 {
+		go db.monitor(c.Health.Interval)
+	}
 
-func Init() (IDB, error) {
+	return IDB(db), nil
+}
 
-	if postgresAddr == "" {
-		return nil, errors.New("$POSTGRES_URL not set")
+// Open connects to Postgresql and Redis, Creates all tables and applies pending migrations.
+// It is used directly by administrative commands that need operations not in IDB.
+func Open(c *config.Config) (*DB, error) {
+	db, err := Connect(c)
+	if err != nil {
+		return nil, err
 	}
 
-	if redisAddr == "" {
-		return nil, errors.New("$REDIS_SERVER not set")
+	// Initialize tables befor returning
+	err = db.createTables()
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	// Connect to Postgresql
-	pq, err := sql.Open("postgres", postgresAddr)
+	err = db.migrate()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
+	return db, nil
+}
+
+// Connect connects to Postgresql and Redis without touching the schema, e.g. to report status of migrations.
+//
+// Connecting to each of them is retried with exponential backoff for it's connect timeout,
+// So the application can start along with it's databases.
+func Connect(c *config.Config) (*DB, error) {
 
-	// Parse REDIS_SERVER and connect to Redis Server
-	redisOpt, err := redis.ParseURL(redisAddr)
+	// Connect to Postgresql
+	pq, err := sql.Open("postgres", c.Postgres.URL)
 	if err != nil {
-		log.Error.Fatalf("Invalid $REDIS_SERVER: %v\n", err)
+		return nil, err
 	}
 
-	client := redis.NewClient(redisOpt)
+	pq.SetMaxOpenConns(c.Postgres.MaxOpenConns)
+	pq.SetMaxIdleConns(c.Postgres.MaxIdleConns)
+	pq.SetConnMaxLifetime(c.Postgres.ConnMaxLifetime)
+	pq.SetConnMaxIdleTime(c.Postgres.ConnMaxIdleTime)
 
-	err = client.Ping().Err()
+	err = retry("Postgresql", c.Postgres.ConnectTimeout, pq.PingContext)
 	if err != nil {
-		log.Error.Fatalf("Error in connecting to redis: %s", err)
+		pq.Close()
+		return nil, err
+	}
+
+	// Parse the URL and connect to Redis Server
+	redisOpt, err := redis.ParseURL(c.Redis.URL)
+	if err != nil {
+		pq.Close()
+		return nil, fmt.Errorf("invalid Redis URL: %s", err)
 	}
 
-	db := &DB{Pq: pq, Redis: client}
+	redisOpt.PoolSize = c.Redis.PoolSize
+	redisOpt.MinIdleConns = c.Redis.MinIdleConns
+	redisOpt.PoolTimeout = c.Redis.PoolTimeout
+	redisOpt.IdleTimeout = c.Redis.IdleTimeout
 
-	// Initialize tables befor returning
-	err = db.createTables()
+	client := redis.NewClient(redisOpt)
+	instrumentRedis(client)
+
+	db := &DB{Pq: pq, Redis: client, hideReports: c.Moderation.HideReports, stopMonitor: make(chan struct{})}
+
+	err = retry("Redis", c.Redis.ConnectTimeout, func(ctx context.Context) error {
+		return db.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+			return c.Ping(ctx)
+		})
+	})
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
-	return IDB(db), nil
+
+	return db, nil
+}
+
+// Close stops monitoring of health and closes the connections to Postgresql and Redis.
+// It waits for queries that have started to finish.
+func (d *DB) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.stopMonitor)
+	})
+
+	pqErr := d.Pq.Close()
+
+	if err := d.Redis.Close(); err != nil {
+		return err
+	}
+	return pqErr
+}
+
+// Queries used to serve feeds and post details.
+// CheckQueryPlans verifies that all of these are served by indexes.
+// Posts are ordered by (timestamp, postid), So posts created in the same instant keep a stable order.
+// Feeds leave out posts with too many reports, See visibleReports.
+const (
+	// Select N most recent posts
+	latestPostsQuery = "SELECT postid, deviceid, post, timestamp, likes_count, comments_count, version FROM posts WHERE reports_count < $2 ORDER BY timestamp DESC, postid DESC LIMIT $1"
+	// Select N posts newer than the specified post and include the specified post.
+	postsAfterQuery = "SELECT postid, deviceid, post, timestamp, likes_count, comments_count, version FROM posts WHERE (timestamp, postid) >= ($1, $2) AND reports_count < $4 ORDER BY timestamp, postid LIMIT $3"
+	// Select N posts older than the specified post and exclude the specified post.
+	postsBeforeQuery = "SELECT postid, deviceid, post, timestamp, likes_count, comments_count, version FROM posts WHERE (timestamp, postid) < ($1, $2) AND reports_count < $4 ORDER BY timestamp DESC, postid DESC LIMIT $3"
+	// Select all comments on a post, Oldest first
+	postCommentsQuery = "SELECT commentid, comment, timestamp FROM comments WHERE postid = $1 ORDER BY timestamp, commentid"
+)
+
+// visibleReports returns the number of reports at which posts are left out of feeds
+func (d *DB) visibleReports() int {
+	if d.hideReports == 0 {
+		return math.MaxInt32
+	}
+	return d.hideReports
 }
 
 // SubmitPost takes a Post, puts it into the database and returns the postid
 func (d *DB) SubmitPost(ctx context.Context, p *Post) error {
 
 	var id int
-	query := fmt.Sprintf("INSERT INTO posts(deviceid, post, timestamp, ipaddr) VALUES ('%s', '%s', '%d', '%s') RETURNING postid", p.DeviceID, p.Text, p.Timestamp, p.IPAddr)
+	query := "INSERT INTO posts(deviceid, post, timestamp, ipaddr) VALUES ($1, $2, $3, $4) RETURNING postid"
 
-	err := d.Pq.QueryRowContext(ctx, query).Scan(&id)
+	err := d.queryRowContext(ctx, query, p.DeviceID, p.Text, p.CreatedAt, p.IPAddr).Scan(&id)
 	if err != nil {
 		return err
 	}
 
-	log.Info.Printf("saved 1 post(%d) from %s\n", id, p.DeviceID)
+	log.FromContext(ctx).With(log.Fields{"postid": id}).Infof("saved post")
 
 	//TODO: Consider returning postid instead of mutating Post
 	p.ID = id
+	p.Timestamp = p.CreatedAt.Unix()
 	return nil
 }
 
 // FetchNPosts takes an integer and returns the most recent N posts
 func (d *DB) FetchNPosts(ctx context.Context, n int) ([]*Post, error) {
-	query := fmt.Sprintf("SELECT postid, deviceid, post, timestamp FROM posts ORDER BY postid DESC LIMIT %d", n)
-
-	rows, err := d.Pq.QueryContext(ctx, query)
+	rows, err := d.queryContext(ctx, latestPostsQuery, n, d.visibleReports())
 	if err != nil {
 		return nil, err
 	}
 
-	p := []*Post{}
-
-	for rows.Next() {
-		post := &Post{}
-
-		err = rows.Scan(&post.ID, &post.DeviceID, &post.Text, &post.Timestamp)
-		if err != nil {
-			return nil, err
-		}
-		p = append(p, post)
-	}
-
-	return p, nil
+	return scanPosts(rows)
 }
 
 // FetchPostsFromID fetches a number of posts before or after the specified id
@@ -127,8 +223,8 @@ func (d *DB) FetchPostsFromID(ctx context.Context, id, limit int, prop string) (
 
 	timestampquery := fmt.Sprintf("SELECT timestamp FROM posts WHERE postid='%d'", id)
 
-	row := d.Pq.QueryRowContext(ctx, timestampquery)
-	var t int64
+	row := d.queryRowContext(ctx, timestampquery)
+	var t time.Time
 
 	err := row.Scan(&t)
 	if err != nil {
@@ -139,119 +235,163 @@ func (d *DB) FetchPostsFromID(ctx context.Context, id, limit int, prop string) (
 		return nil, err
 	}
 
-	var query string
+	query := postsBeforeQuery
 	if prop == "after" {
-		// Select N posts newer than the specified post and include the specified post.
-		query = fmt.Sprintf("SELECT postid, deviceid, post, timestamp FROM posts WHERE timestamp >= %d AND postid >= %d LIMIT %d", t, id, limit)
-	} else {
-		// Select N posts older than the specified post and exclude the specified post.
-		query = fmt.Sprintf("SELECT postid, deviceid, post, timestamp FROM posts WHERE timestamp < %d AND postid <= %d ORDER BY postid DESC LIMIT %d", t, id, limit)
+		query = postsAfterQuery
 	}
-	rows, err := d.Pq.QueryContext(ctx, query)
+	rows, err := d.queryContext(ctx, query, t, id, limit, d.visibleReports())
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 
+	return scanPosts(rows)
+}
+
+// scanPosts reads all the posts from rows and closes it.
+// Columns must be postid, deviceid, post, timestamp, likes_count, comments_count, version in that order.
+func scanPosts(rows *sql.Rows) ([]*Post, error) {
+	defer rows.Close()
+
 	p := []*Post{}
 
 	for rows.Next() {
 		post := &Post{}
 
-		err := rows.Scan(&post.ID, &post.DeviceID, &post.Text, &post.Timestamp)
+		err := rows.Scan(&post.ID, &post.DeviceID, &post.Text, &post.CreatedAt, &post.LikesCount, &post.CommentsCount, &post.Version)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return nil, nil
-			}
 			return nil, err
 		}
+		post.Timestamp = post.CreatedAt.Unix()
+
 		p = append(p, post)
 	}
 
-	return p, nil
+	return p, rows.Err()
 }
 
-// Report puts information like postid and device id in reports table
-func (d *DB) Report(ctx context.Context, postid, deviceid, reason string) error {
+// Report puts information like postid and device id in reports table and returns the number of reports on the post.
+// ErrInvalidPostID is returned if the postid doesn't reference an existing post.
+func (d *DB) Report(ctx context.Context, postid, deviceid, reason string) (int, error) {
 
-	postExistsQuery := fmt.Sprintf("SELECT postid FROM posts where postid='%s'", postid)
+	// reports_count is incremented by a trigger that runs after the snapshot of the query is taken, So it's counted here
+	query := `WITH r AS (INSERT INTO reports(postid, deviceid, reason) VALUES ($1, $2, $3) RETURNING postid)
+		SELECT p.reports_count + 1 FROM posts p JOIN r ON p.postid = r.postid`
 
-	pid := 0
-	// Verify that the specified postid exists
-	err := d.Pq.QueryRowContext(ctx, postExistsQuery).Scan(&pid)
+	var n int
+	err := d.queryRowContext(ctx, query, postid, deviceid, reason).Scan(&n)
 	if err != nil {
-		return err
+		return 0, mapPostError(err)
 	}
 
-	query := fmt.Sprintf("INSERT INTO reports(postid, deviceid, reason) VALUES ('%s', '%s', '%s')", postid, deviceid, reason)
+	log.FromContext(ctx).With(log.Fields{"postid": postid}).Infof("saved report")
 
-	_, err = d.Pq.ExecContext(ctx, query)
+	return n, nil
+}
+
+// LikePost adds a new entry in likes table containing details like deviceid and postid and returns the new number of likes on the post.
+// likes_count of the post is incremented by a trigger on likes table.
+// ErrInvalidPostID is returned if the postid doesn't reference an existing post and ErrAlreadyLiked if the device has already liked it.
+func (d *DB) LikePost(ctx context.Context, postid string, deviceid string) (int, error) {
+
+	query := "INSERT INTO likes(postid, deviceid) VALUES ($1, $2)"
+
+	_, err := d.execContext(ctx, query, postid, deviceid)
 	if err != nil {
-		return err
-	}
+		if perr, ok := err.(*pq.Error); ok && perr.Code.Name() == "unique_violation" {
+			return 0, ErrAlreadyLiked
+		}
 
-	log.Info.Printf("Saved report for %s from %s", postid, deviceid)
+		return 0, mapPostError(err)
+	}
 
-	return nil
+	return d.fetchLikes(ctx, postid)
 }
 
-// LikePost adds a new entry in likes table containing details like deviceid and postid.
-// When complete details of a post are requested, We'll have to count all the entries containing specified postid.
-func (d *DB) LikePost(ctx context.Context, postid string, deviceid string) error {
+// EditPost replaces text of a post and returns the edited post.
+// Only the device that submitted a post can edit it, ErrNotOwner is returned for every other device.
+func (d *DB) EditPost(ctx context.Context, postid, deviceid, text string) (*Post, error) {
 
-	query := fmt.Sprintf("INSERT INTO likes(postid, deviceid) VALUES('%s', '%s')", postid, deviceid)
+	query := "UPDATE posts SET post = $1 WHERE postid = $2 AND deviceid = $3 RETURNING postid, deviceid, post, timestamp, likes_count, comments_count, version"
 
-	p, err := d.FetchPost(ctx, postid)
+	rows, err := d.queryContext(ctx, query, text, postid, deviceid)
 	if err != nil {
-		return err
+		return nil, mapPostError(err)
+	}
+
+	p, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if p == nil {
-		return errors.New(ErrInvalidPostID)
+	if len(p) == 1 {
+		log.FromContext(ctx).With(log.Fields{"postid": postid}).Infof("edited post")
+		return p[0], nil
 	}
 
-	_, err = d.Pq.ExecContext(ctx, query)
+	// Nothing was updated, Either the post doesn't exist or it belongs to some other device
+	post, err := d.FetchPost(ctx, postid)
 	if err != nil {
-		log.Warn.Println(err.(pq.Error).Code.Name())
-		return err
+		return nil, err
 	}
 
-	return nil
+	if post == nil {
+		return nil, ErrInvalidPostID
+	}
+
+	return nil, ErrNotOwner
 }
 
-func (d *DB) fetchComments(ctx context.Context, postid string) ([]*Comment, error) {
-	query := fmt.Sprintf("SELECT commentid, comment, timestamp FROM comments WHERE postid='%s'", postid)
+// Comment puts a comment on a post in comments table and sets ID of the comment.
+// comments_count of the post is incremented by a trigger on comments table.
+// ErrInvalidPostID is returned if the postid doesn't reference an existing post.
+func (d *DB) Comment(ctx context.Context, postid string, c *Comment) error {
+
+	query := "INSERT INTO comments(postid, deviceid, timestamp, comment) VALUES ($1, $2, $3, $4) RETURNING commentid"
+
+	err := d.queryRowContext(ctx, query, postid, c.DeviceID, c.CreatedAt, c.Text).Scan(&c.ID)
+	if err != nil {
+		return mapPostError(err)
+	}
+
+	log.FromContext(ctx).With(log.Fields{"postid": postid, "commentid": c.ID}).Infof("saved comment")
+
+	c.Timestamp = c.CreatedAt.Unix()
+	return nil
+}
 
+// FetchPostComments returns all the comments on a post, Oldest first
+func (d *DB) FetchPostComments(ctx context.Context, postid string) ([]*Comment, error) {
 	c := []*Comment{}
 
-	rows, err := d.Pq.QueryContext(ctx, query)
+	rows, err := d.queryContext(ctx, postCommentsQuery, postid)
 	if err != nil {
-		return nil, err
+		return nil, mapPostError(err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		co := &Comment{}
 
-		err = rows.Scan(&co.ID, &co.Text, &co.Timestamp)
+		err = rows.Scan(&co.ID, &co.Text, &co.CreatedAt)
 
 		if err != nil {
 			return nil, err
 		}
+		co.Timestamp = co.CreatedAt.Unix()
 
 		c = append(c, co)
 	}
 
-	return c, nil
+	return c, rows.Err()
 }
 
+// fetchLikes reads the likes counter of a post, That is maintained by a trigger on likes table
 func (d *DB) fetchLikes(ctx context.Context, postid string) (int, error) {
-	query := fmt.Sprintf("SELECT count(*) FROM likes WHERE postid='%s'", postid)
+	query := "SELECT likes_count FROM posts WHERE postid = $1"
 
 	likes := 0
 
-	err := d.Pq.QueryRowContext(ctx, query).Scan(&likes)
+	err := d.queryRowContext(ctx, query, postid).Scan(&likes)
 	if err != nil {
 		return 0, err
 	}
@@ -259,13 +399,14 @@ func (d *DB) fetchLikes(ctx context.Context, postid string) (int, error) {
 	return likes, nil
 }
 
+// FetchPost returns a single post, Or nil if it doesn't exist
 func (d *DB) FetchPost(ctx context.Context, postid string) (*Post, error) {
 
-	query := fmt.Sprintf("SELECT postid, deviceid, post, timestamp, ipaddr FROM posts WHERE postid='%s'", postid)
+	query := "SELECT postid, deviceid, post, timestamp, ipaddr, likes_count, comments_count, reports_count, version FROM posts WHERE postid = $1"
 
 	p := &Post{}
 
-	err := d.Pq.QueryRowContext(ctx, query).Scan(&p.ID, &p.DeviceID, &p.Text, &p.Timestamp, &p.IPAddr)
+	err := d.queryRowContext(ctx, query, postid).Scan(&p.ID, &p.DeviceID, &p.Text, &p.CreatedAt, &p.IPAddr, &p.LikesCount, &p.CommentsCount, &p.ReportsCount, &p.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -273,42 +414,73 @@ func (d *DB) FetchPost(ctx context.Context, postid string) (*Post, error) {
 
 		return nil, err
 	}
+	p.Timestamp = p.CreatedAt.Unix()
 
 	return p, nil
 }
 
+// recountQuery sets every counter on posts to the actual number of rows referencing the post,
+// Only posts whose counters have drifted are updated.
+const recountQuery = `UPDATE posts SET likes_count = c.likes, comments_count = c.comments, reports_count = c.reports
+	FROM (SELECT p.postid,
+		(SELECT count(*) FROM likes l WHERE l.postid = p.postid) AS likes,
+		(SELECT count(*) FROM comments c WHERE c.postid = p.postid) AS comments,
+		(SELECT count(*) FROM reports r WHERE r.postid = p.postid) AS reports
+		FROM posts p) c
+	WHERE posts.postid = c.postid
+	AND (posts.likes_count, posts.comments_count, posts.reports_count) IS DISTINCT FROM (c.likes::int, c.comments::int, c.reports::int)`
+
+// Recount recomputes likes_count, comments_count and reports_count of all posts.
+// Counters are normally kept correct by triggers, This is used to repair any drift.
+func (d *DB) Recount(ctx context.Context) (int64, error) {
+
+	res, err := d.execContext(ctx, recountQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	log.FromContext(ctx).Infof("Recounted engagement counters, repaired %d posts", n)
+
+	return n, nil
+}
+
 func (d *DB) createTables() error {
 
-	log.Info.Println("Creating Tables")
+	log.Infof("Creating Tables")
 
-	log.Info.Println("Creating reports table")
+	log.Infof("Creating reports table")
 	err := d.createTableHelper("CREATE TABLE reports(reportid SERIAL PRIMARY KEY, postid INTEGER NOT NULL, deviceid VARCHAR NOT NULL, reason VARCHAR NOT NULL)")
 	if err != nil {
 		return err
 	}
 
-	log.Info.Println("Creating posts table")
+	log.Infof("Creating posts table")
 	err = d.createTableHelper("CREATE TABLE posts (postid SERIAL PRIMARY KEY, deviceid VARCHAR NOT NULL, post VARCHAR NOT NULL, timestamp INTEGER NOT NULL, ipaddr VARCHAR NOT NULL)")
 	if err != nil {
 		return err
 	}
-	log.Info.Println("Created posts table")
+	log.Infof("Created posts table")
 
-	log.Info.Println("Creating Comments Table")
+	log.Infof("Creating Comments Table")
 	err = d.createTableHelper("CREATE TABLE comments(commentid SERIAL PRIMARY KEY, postid INTEGER NOT NULL, deviceid VARCHAR NOT NULL, timestamp INTEGER NOT NULL, comment VARCHAR NOT NULL)")
 	if err != nil {
 		return err
 	}
-	log.Info.Println("Created Comments Table")
+	log.Infof("Created Comments Table")
 
-	log.Info.Println("Creating Likes Table")
+	log.Infof("Creating Likes Table")
 	err = d.createTableHelper("CREATE TABLE likes(postid INTEGER NOT NULL, deviceid VARCHAR NOT NULL, PRIMARY KEY(postid, deviceid))")
 	if err != nil {
 		return err
 	}
-	log.Info.Println("Created Likes Table")
+	log.Infof("Created Likes Table")
 
-	log.Info.Printf("Tables Created...")
+	log.Infof("Tables Created...")
 
 	return nil
 }
@@ -320,7 +492,7 @@ func (d *DB) createTableHelper(stmt string) error {
 			if perr.Code.Name() != "duplicate_table" {
 				return perr
 			}
-			log.Warn.Printf("%s: %s", perr.Code.Name(), perr.Error())
+			log.Warnf("%s: %s", perr.Code.Name(), perr.Error())
 			return nil
 		}
 		return err
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestDB creates a throwaway database and returns a DB connected to it with every migration applied.
+// The database is dropped when the test ends.
+//
+// Tests using it need a Postgres server, $ENVELOPE_TEST_POSTGRES_URL is a URL of it that can create databases,
+// e.g. postgres://postgres@localhost:5432/postgres?sslmode=disable. They are skipped when it's not set.
+// Only Postgres is connected, Redis is nil.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	server := os.Getenv("ENVELOPE_TEST_POSTGRES_URL")
+	if server == "" {
+		t.Skip("$ENVELOPE_TEST_POSTGRES_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := fmt.Sprintf("envelope_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		admin.Close()
+		t.Fatalf("error in creating test database: %s", err)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+
+	pq, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		pq.Close()
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name); err != nil {
+			t.Errorf("error in dropping test database %s: %s", name, err)
+		}
+		admin.Close()
+	})
+
+	d := &DB{Pq: pq, stopMonitor: make(chan struct{})}
+
+	if err := d.createTables(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+// newTestRedis returns a DB connected only to the Redis server at $ENVELOPE_TEST_REDIS_URL, Postgres is nil.
+// Tests using it are skipped when it's not set. They must only use keys prefixed with envelope:test:.
+func newTestRedis(t *testing.T) *DB {
+	t.Helper()
+
+	server := os.Getenv("ENVELOPE_TEST_REDIS_URL")
+	if server == "" {
+		t.Skip("$ENVELOPE_TEST_REDIS_URL is not set")
+	}
+
+	opt, err := redis.ParseURL(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(opt)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return &DB{Redis: client, stopMonitor: make(chan struct{})}
+}
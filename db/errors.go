@@ -1,9 +1,41 @@
 package db
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Errors returned by database operations.
+// These are sentinel errors, Check for them with errors.Is since they may be wrapped with more details.
 var (
-	// ErrInvalidData is sent when a value in request is invalid
-	ErrInvalidData = "INVALID_DATA"
+	// ErrNotRegistered is returned when a deviceid is not registered
+	ErrNotRegistered = errors.New("deviceid is not registered")
+
+	// ErrInvalidPostID is returned when a postid doesn't reference an existing post
+	ErrInvalidPostID = errors.New("postid doesn't reference an existing post")
 
-	// ErrNotRegistered is sent when a deviceid is not registered
-	ErrNotRegistered = "NOT_REGISTERED"
+	// ErrAlreadyLiked is returned when a device likes a post it has already liked
+	ErrAlreadyLiked = errors.New("post is already liked by deviceid")
+
+	// ErrNotOwner is returned when a device modifies a post submitted by some other device
+	ErrNotOwner = errors.New("post was not submitted by deviceid")
+
+	// ErrEventsTrimmed is returned when events after an event ID can't be replayed,
+	// Because the event has been trimmed from the events log or it never existed.
+	ErrEventsTrimmed = errors.New("events after event id are no longer retained")
 )
+
+// mapPostError maps errors caused by a postid that doesn't reference an existing post to ErrInvalidPostID.
+// That is a foreign key violation or a postid that is not a valid integer.
+func mapPostError(err error) error {
+	if perr, ok := err.(*pq.Error); ok {
+		switch perr.Code.Name() {
+		case "foreign_key_violation", "invalid_text_representation", "numeric_value_out_of_range":
+			return fmt.Errorf("%w: %s", ErrInvalidPostID, perr.Message)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+const (
+	// Delay before the first retry of connecting, It doubles after every failure up to retryMaxDelay
+	retryInitialDelay = 250 * time.Millisecond
+	retryMaxDelay     = 5 * time.Second
+)
+
+// HealthCheck is the result of checking a dependency of the application
+type HealthCheck struct {
+	// Name of the dependency, postgres, redis or migrations
+	Name    string
+	Err     error
+	Latency time.Duration
+}
+
+// Health checks Postgres, Redis and the schema concurrently, ctx limits how long the checks can take.
+// Postgres is checked with a ping and a trivial query, Redis with PING.
+// The schema is healthy when every migration known to this binary has been applied.
+func (d *DB) Health(ctx context.Context) []HealthCheck {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"postgres", d.checkPostgres},
+		{"redis", d.checkRedis},
+		{"migrations", d.checkMigrations},
+	}
+
+	results := make([]HealthCheck, len(checks))
+	done := make(chan struct{}, len(checks))
+
+	for i, c := range checks {
+		go func(i int, name string, check func(ctx context.Context) error) {
+			start := time.Now()
+			err := check(ctx)
+			results[i] = HealthCheck{Name: name, Err: err, Latency: time.Since(start)}
+			done <- struct{}{}
+		}(i, c.name, c.check)
+	}
+
+	for range checks {
+		<-done
+	}
+
+	return results
+}
+
+func (d *DB) checkPostgres(ctx context.Context) error {
+	if err := d.Pq.PingContext(ctx); err != nil {
+		return err
+	}
+
+	var one int
+	return d.queryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+func (d *DB) checkRedis(ctx context.Context) error {
+	return d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		return c.Ping(ctx)
+	})
+}
+
+// checkMigrations fails when the schema is behind this binary.
+// A schema ahead of it is fine, That happens while a newer version is being rolled out.
+func (d *DB) checkMigrations(ctx context.Context) error {
+	current, latest, err := d.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf("schema is at version %d, %d migrations are pending", current, latest-current)
+	}
+
+	return nil
+}
+
+// retry calls connect until it succeeds or timeout passes, Waiting twice as long after every failure.
+// Delays are jittered so instances started together don't retry together.
+func retry(name string, timeout time.Duration, connect func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	delay := retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if deadline, _ := ctx.Deadline(); time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("error in connecting to %s, Gave up after %d attempts within connect timeout of %s: %s", name, attempt, timeout, err)
+		}
+
+		log.Warnf("Error in connecting to %s, Retrying in %s: %s", name, wait.Round(time.Millisecond), err)
+		time.Sleep(wait)
+
+		delay *= 2
+		if delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+}
+
+// monitor checks health of the dependencies every interval until d is closed.
+// A dependency is reported when it becomes degraded and when it recovers, It's status is exported in metrics too.
+func (d *DB) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	degraded := map[string]bool{}
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		checks := d.Health(ctx)
+		cancel()
+
+		for _, c := range checks {
+			l := log.With(log.Fields{"dependency": c.Name, "latency_ms": c.Latency.Milliseconds()})
+
+			switch {
+			case c.Err != nil && !degraded[c.Name]:
+				l.Warnf("%s is degraded: %s", c.Name, c.Err)
+			case c.Err == nil && degraded[c.Name]:
+				l.Infof("%s has recovered", c.Name)
+			}
+
+			degraded[c.Name] = c.Err != nil
+			dependencyUp.WithLabelValues(c.Name).Set(boolToFloat(c.Err == nil))
+		}
+
+		select {
+		case <-d.stopMonitor:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
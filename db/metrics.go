@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// redisCommandDuration is the latency of every Redis command run by DB, By name of the command
+var redisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "envelope",
+	Subsystem: "redis",
+	Name:      "command_duration_seconds",
+	Help:      "Latency of Redis commands.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"command"})
+
+// dependencyUp is 1 when a dependency passed it's last check, It's set by monitor
+var dependencyUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "envelope",
+	Name:      "dependency_up",
+	Help:      "Whether a dependency passed it's last health check, By name of the dependency.",
+}, []string{"dependency"})
+
+// instrumentRedis observes latency of every command run by client
+func instrumentRedis(client *redis.Client) {
+	client.AddHook(redisMetrics{})
+}
+
+// redisMetrics is a redis.Hook observing latency of commands, Pipelines are not used
+type redisMetrics struct{}
+
+// commandStart is the key of the time a command was started at in it's context
+type commandStart struct{}
+
+func (redisMetrics) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, commandStart{}, time.Now()), nil
+}
+
+func (redisMetrics) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if start, ok := ctx.Value(commandStart{}).(time.Time); ok {
+		redisCommandDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+func (redisMetrics) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (redisMetrics) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// Collectors returns collectors of Postgres and Redis connection pool statistics, Redis command latencies
+// and health of the dependencies
+func (d *DB) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		collectors.NewDBStatsCollector(d.Pq, "envelope"),
+		&redisPoolCollector{d: d},
+		redisCommandDuration,
+		dependencyUp,
+	}
+}
+
+var (
+	redisHitsDesc     = poolDesc("pool_hits_total", "Number of times a free connection was found in the Redis pool.")
+	redisMissesDesc   = poolDesc("pool_misses_total", "Number of times a free connection was not found in the Redis pool.")
+	redisTimeoutsDesc = poolDesc("pool_timeouts_total", "Number of times waiting for a connection from the Redis pool timed out.")
+	redisTotalDesc    = poolDesc("pool_connections", "Number of connections in the Redis pool.")
+	redisIdleDesc     = poolDesc("pool_idle_connections", "Number of idle connections in the Redis pool.")
+	redisStaleDesc    = poolDesc("pool_stale_connections_total", "Number of stale connections removed from the Redis pool.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName("envelope", "redis", name), help, nil, nil)
+}
+
+// redisPoolCollector reads statistics of the Redis connection pool on every scrape
+type redisPoolCollector struct {
+	d *DB
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		redisHitsDesc, redisMissesDesc, redisTimeoutsDesc, redisTotalDesc, redisIdleDesc, redisStaleDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	rs := c.d.Redis.PoolStats()
+
+	ch <- prometheus.MustNewConstMetric(redisHitsDesc, prometheus.CounterValue, float64(rs.Hits))
+	ch <- prometheus.MustNewConstMetric(redisMissesDesc, prometheus.CounterValue, float64(rs.Misses))
+	ch <- prometheus.MustNewConstMetric(redisTimeoutsDesc, prometheus.CounterValue, float64(rs.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisTotalDesc, prometheus.GaugeValue, float64(rs.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisIdleDesc, prometheus.GaugeValue, float64(rs.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisStaleDesc, prometheus.CounterValue, float64(rs.StaleConns))
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// migration is a single schema change. Migrations are applied in order of their version
+// and every applied version is recorded in schema_migrations, So each of them runs exactly once.
+type migration struct {
+	version int
+	name    string
+	stmts   []string
+}
+
+// migrations holds every schema change made after the initial tables created in createTables.
+// New migrations must be appended with the next version number, Existing ones must never be edited.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "engagement counters",
+		stmts: []string{
+			"ALTER TABLE posts ADD COLUMN IF NOT EXISTS likes_count INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_count INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE posts ADD COLUMN IF NOT EXISTS reports_count INTEGER NOT NULL DEFAULT 0",
+			// bump_post_counter keeps the counter column named in its first argument in sync
+			// with the number of rows referencing a post in the table it is attached to.
+			`CREATE OR REPLACE FUNCTION bump_post_counter() RETURNS trigger AS $$
+			BEGIN
+				IF TG_OP = 'INSERT' THEN
+					EXECUTE format('UPDATE posts SET %I = %I + 1 WHERE postid = $1', TG_ARGV[0], TG_ARGV[0]) USING NEW.postid;
+					RETURN NEW;
+				END IF;
+
+				EXECUTE format('UPDATE posts SET %I = greatest(%I - 1, 0) WHERE postid = $1', TG_ARGV[0], TG_ARGV[0]) USING OLD.postid;
+				RETURN OLD;
+			END;
+			$$ LANGUAGE plpgsql`,
+			"DROP TRIGGER IF EXISTS likes_count_trigger ON likes",
+			"CREATE TRIGGER likes_count_trigger AFTER INSERT OR DELETE ON likes FOR EACH ROW EXECUTE PROCEDURE bump_post_counter('likes_count')",
+			"DROP TRIGGER IF EXISTS comments_count_trigger ON comments",
+			"CREATE TRIGGER comments_count_trigger AFTER INSERT OR DELETE ON comments FOR EACH ROW EXECUTE PROCEDURE bump_post_counter('comments_count')",
+			"DROP TRIGGER IF EXISTS reports_count_trigger ON reports",
+			"CREATE TRIGGER reports_count_trigger AFTER INSERT OR DELETE ON reports FOR EACH ROW EXECUTE PROCEDURE bump_post_counter('reports_count')",
+			// Backfill counters for posts created before the triggers existed
+			recountQuery,
+		},
+	},
+	{
+		version: 2,
+		name:    "timestamptz timestamps",
+		stmts: []string{
+			// Existing values are Unix seconds
+			"ALTER TABLE posts ALTER COLUMN timestamp TYPE TIMESTAMPTZ USING to_timestamp(timestamp)",
+			"ALTER TABLE posts ALTER COLUMN timestamp SET DEFAULT now()",
+			"ALTER TABLE comments ALTER COLUMN timestamp TYPE TIMESTAMPTZ USING to_timestamp(timestamp)",
+			"ALTER TABLE comments ALTER COLUMN timestamp SET DEFAULT now()",
+			"CREATE INDEX IF NOT EXISTS posts_timestamp_postid_idx ON posts(timestamp, postid)",
+		},
+	},
+	{
+		version: 3,
+		name:    "feed, comment and report indexes",
+		stmts: []string{
+			// Comments are always fetched for a single post, oldest first
+			"CREATE INDEX IF NOT EXISTS comments_postid_timestamp_idx ON comments(postid, timestamp, commentid)",
+			"CREATE INDEX IF NOT EXISTS reports_postid_idx ON reports(postid)",
+			// Likes are looked up by (postid, deviceid) through the primary key, This serves lookups by device
+			"CREATE INDEX IF NOT EXISTS likes_deviceid_idx ON likes(deviceid)",
+			"CREATE INDEX IF NOT EXISTS posts_deviceid_idx ON posts(deviceid)",
+		},
+	},
+	{
+		version: 4,
+		name:    "post foreign keys",
+		stmts: []string{
+			// Remove rows referencing posts that don't exist, Otherwise the constraints can't be added
+			"DELETE FROM likes WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.postid = likes.postid)",
+			"DELETE FROM comments WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.postid = comments.postid)",
+			"DELETE FROM reports WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.postid = reports.postid)",
+			// Likes, Comments and Reports of a post are deleted along with it
+			"ALTER TABLE likes ADD CONSTRAINT likes_postid_fkey FOREIGN KEY (postid) REFERENCES posts(postid) ON DELETE CASCADE",
+			"ALTER TABLE comments ADD CONSTRAINT comments_postid_fkey FOREIGN KEY (postid) REFERENCES posts(postid) ON DELETE CASCADE",
+			"ALTER TABLE reports ADD CONSTRAINT reports_postid_fkey FOREIGN KEY (postid) REFERENCES posts(postid) ON DELETE CASCADE",
+		},
+	},
+	{
+		version: 5,
+		name:    "banned devices",
+		stmts: []string{
+			"CREATE TABLE IF NOT EXISTS banned_devices(deviceid VARCHAR PRIMARY KEY, reason VARCHAR NOT NULL, banned_at TIMESTAMPTZ NOT NULL DEFAULT now())",
+		},
+	},
+	{
+		version: 6,
+		name:    "post versions",
+		stmts: []string{
+			"ALTER TABLE posts ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0",
+			// bump_post_version increments version of a post whenever something sent to clients changes,
+			// Text or counters of likes and comments. That includes comments being added, They bump comments_count.
+			`CREATE OR REPLACE FUNCTION bump_post_version() RETURNS trigger AS $$
+			BEGIN
+				IF (NEW.post, NEW.likes_count, NEW.comments_count) IS DISTINCT FROM (OLD.post, OLD.likes_count, OLD.comments_count) THEN
+					NEW.version := OLD.version + 1;
+				END IF;
+				RETURN NEW;
+			END;
+			$$ LANGUAGE plpgsql`,
+			"DROP TRIGGER IF EXISTS posts_version_trigger ON posts",
+			"CREATE TRIGGER posts_version_trigger BEFORE UPDATE ON posts FOR EACH ROW EXECUTE PROCEDURE bump_post_version()",
+		},
+	},
+}
+
+// migrationLock is the key of the advisory lock held while migrating,
+// So instances started together apply each migration only once. It's an arbitrary number unique to this application.
+const migrationLock = 4_630_713_285_102_117
+
+// migrate applies all the pending migrations, Each one in it's own transaction.
+// An advisory lock is held on a dedicated connection throughout, Other instances wait for it and then find nothing pending.
+func (d *DB) migrate() error {
+	ctx := context.Background()
+
+	conn, err := d.Pq.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock)
+	if err != nil {
+		return fmt.Errorf("error in taking migration lock: %s", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations(version INTEGER PRIMARY KEY, name VARCHAR NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())")
+	if err != nil {
+		return err
+	}
+
+	// Read after taking the lock, Migrations applied by another instance while waiting are skipped
+	current := 0
+	err = conn.QueryRowContext(ctx, "SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		log.Infof("Applying migration %d: %s", m.version, m.name)
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range m.stmts {
+			_, err = tx.Exec(stmt)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		_, err = tx.Exec("INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", m.version, m.name)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Applied migration %d", m.version)
+	}
+
+	return nil
+}
@@ -1,11 +1,18 @@
 package db
 
+import "time"
+
+// Post is a single post. Timestamp is kept in Unix seconds for older clients,
+// CreatedAt carries the complete time and is serialized in RFC 3339 format.
 type Post struct {
-	ID        int    `db:"postid" json:"postid"`
-	Text      string `db:"post" json:"post"`
-	Timestamp int64  `db:"timestamp" json:"timestamp"`
-	DeviceID  string `db:"deviceid" json:"-"`
-	IPAddr    string `db:"ipAddr" json:"-"`
+	ID        int       `db:"postid" json:"postid"`
+	Text      string    `db:"post" json:"post"`
+	Timestamp int64     `db:"-" json:"timestamp"`
+	CreatedAt time.Time `db:"timestamp" json:"created_at"`
+	DeviceID  string    `db:"deviceid" json:"-"`
+	IPAddr    string    `db:"ipAddr" json:"-"`
+	// Version is incremented whenever text, likes or comments of the post change, See migration 6
+	Version int `db:"version" json:"-"`
 	PostMeta
 }
 
@@ -14,14 +21,16 @@ type PostMeta struct {
 	Editable      bool       `json:"editable"`
 	CommentsCount int        `db:"comments" json:"comments_count"`
 	LikesCount    int        `db:"likes" json:"likes_count"`
+	ReportsCount  int        `db:"reports" json:"-"`
 	Comments      []*Comment `json:"comments"`
 }
 
 type Comment struct {
-	ID        int    `db:"commentid" json:"commentid"`
-	Text      string `db:"comment" json:"comment"`
-	Timestamp int64  `db:"timestamp" json:"timestamp"`
-	DeviceID  string `db:"deviceid" json:"-"`
+	ID        int       `db:"commentid" json:"commentid"`
+	Text      string    `db:"comment" json:"comment"`
+	Timestamp int64     `db:"-" json:"timestamp"`
+	CreatedAt time.Time `db:"timestamp" json:"created_at"`
+	DeviceID  string    `db:"deviceid" json:"-"`
 }
 
 type Like struct {
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// planNode is a single node of the plan returned by EXPLAIN (FORMAT JSON)
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// seqScans returns names of all the relations that are read with a sequential scan in the plan
+func (p planNode) seqScans() []string {
+	s := []string{}
+
+	if p.NodeType == "Seq Scan" {
+		s = append(s, p.RelationName)
+	}
+
+	for _, c := range p.Plans {
+		s = append(s, c.seqScans()...)
+	}
+
+	return s
+}
+
+// CheckQueryPlans runs EXPLAIN on all the feed queries and returns an error listing every query
+// that reads a table with a sequential scan. It's only meaningful on a large dataset,
+// On small tables the planner correctly prefers sequential scans. TestQueryPlans runs it on a seeded throwaway database.
+func (d *DB) CheckQueryPlans(ctx context.Context) error {
+
+	// Pick a post from the middle of the table, So both after and before pages have rows to read
+	var (
+		postid int
+		t      time.Time
+	)
+	err := d.Pq.QueryRowContext(ctx, "SELECT postid, timestamp FROM posts ORDER BY postid OFFSET (SELECT count(*) / 2 FROM posts) LIMIT 1").Scan(&postid, &t)
+	if err != nil {
+		return fmt.Errorf("error in picking a post to explain queries with, Is the database seeded? %s", err)
+	}
+
+	queries := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{"latest posts", latestPostsQuery, []interface{}{20, d.visibleReports()}},
+		{"posts after", postsAfterQuery, []interface{}{t, postid, 20, d.visibleReports()}},
+		{"posts before", postsBeforeQuery, []interface{}{t, postid, 20, d.visibleReports()}},
+		{"post comments", postCommentsQuery, []interface{}{postid}},
+	}
+
+	failed := []string{}
+
+	for _, q := range queries {
+		var plan string
+
+		err := d.Pq.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+q.query, q.args...).Scan(&plan)
+		if err != nil {
+			return fmt.Errorf("error in explaining %s: %s", q.name, err)
+		}
+
+		p := []struct {
+			Plan planNode `json:"Plan"`
+		}{}
+
+		err = json.Unmarshal([]byte(plan), &p)
+		if err != nil {
+			return fmt.Errorf("error in parsing plan of %s: %s", q.name, err)
+		}
+
+		for _, e := range p {
+			if s := e.Plan.seqScans(); len(s) > 0 {
+				failed = append(failed, fmt.Sprintf("%s (sequential scan on %s)", q.name, strings.Join(s, ", ")))
+			}
+		}
+
+		log.Infof("Checked plan of %s", q.name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("queries not served by an index: %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestQueryPlans seeds a throwaway database with a million posts and fails when any feed query
+// reads a table with a sequential scan. It needs a Postgres server, See newTestDB. Seeding takes a few minutes.
+func TestQueryPlans(t *testing.T) {
+	if testing.Short() {
+		t.Skip("seeding a million posts is slow")
+	}
+
+	d := newTestDB(t)
+	ctx := context.Background()
+
+	// 1M posts, About 1.2M likes, 500k comments and 10k reports
+	err := d.Seed(ctx, SeedOptions{
+		Posts:       1000000,
+		Devices:     50000,
+		Period:      365 * 24 * time.Hour,
+		Likes:       1.5,
+		Comments:    0.6,
+		ReportRatio: 0.01,
+		Seed:        1,
+	})
+	if err != nil {
+		t.Fatalf("error in seeding: %s", err)
+	}
+
+	var rows int
+	err = d.Pq.QueryRow("SELECT (SELECT count(*) FROM posts) + (SELECT count(*) FROM likes) + (SELECT count(*) FROM comments) + (SELECT count(*) FROM reports)").Scan(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Seeded %d rows", rows)
+
+	if err := d.CheckQueryPlans(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeqScans(t *testing.T) {
+	tests := []struct {
+		name string
+		plan string
+		want []string
+	}{
+		{
+			name: "index scan",
+			plan: `{"Node Type": "Limit", "Plans": [{"Node Type": "Index Scan", "Relation Name": "posts"}]}`,
+			want: []string{},
+		},
+		{
+			name: "nested sequential scan",
+			plan: `{"Node Type": "Limit", "Plans": [{"Node Type": "Sort", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "posts"}]}]}`,
+			want: []string{"posts"},
+		},
+		{
+			name: "sequential scans on both sides of a join",
+			plan: `{"Node Type": "Hash Join", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "posts"}, {"Node Type": "Hash", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "comments"}]}]}`,
+			want: []string{"posts", "comments"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p planNode
+			if err := json.Unmarshal([]byte(tt.plan), &p); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.seqScans(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("seqScans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,30 +2,153 @@ package db
 
 import (
 	"context"
-	"errors"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
-// Redis Client in DB has context set from Handle function in Router
+// redis runs a command built by cmd with ctx and returns it's error, Results of cmd must be read only when it's nil.
+// go-redis closes the connection of a command when ctx is done, So a cancelled command doesn't keep running
+// and holding it's connection. Redis drops a blocked command, e.g. BLPOP, when it's connection is closed.
+// Every command gets a span, A child of the span in ctx.
+func (d *DB) redis(ctx context.Context, cmd func(ctx context.Context, c *redis.Client) redis.Cmder) error {
+	ctx, span := startRedisSpan(ctx)
+
+	c := cmd(ctx, d.Redis)
+	endRedisSpan(span, c)
+
+	return c.Err()
+}
 
 // VerifyDeviceID takes a Device ID and checks if it registered via checking it's existence in Redis.
 func (d *DB) VerifyDeviceID(ctx context.Context, deviceid string) (string, error) {
 
-	h, err := d.Redis.Get(deviceid).Result()
+	var get *redis.StringCmd
+	err := d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		get = c.Get(ctx, deviceid)
+		return get
+	})
 	if err != nil {
 
 		if err == redis.Nil {
-			return "", errors.New(ErrNotRegistered)
+			return "", ErrNotRegistered
 		}
 		return "", err
 	}
 
-	return h, nil
+	return get.Val(), nil
 }
 
 // RegisterDeviceID takes a device id and a hash and saves it in database
 func (d *DB) RegisterDeviceID(ctx context.Context, deviceid string, hash string, t time.Duration) error {
-	return d.Redis.Set(deviceid, hash, t).Err()
+	return d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		return c.Set(ctx, deviceid, hash, t)
+	})
+}
+
+// CountRequest counts a request of deviceid to do action and returns the number of them made in the current window.
+// A window starts with the first request after the previous one has expired.
+func (d *DB) CountRequest(ctx context.Context, deviceid, action string, window time.Duration) (int64, error) {
+	key := "envelope:ratelimit:" + action + ":" + deviceid
+
+	// Both run in a transaction, So a count is never left without an expiry
+	var incr *redis.IntCmd
+	err := d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		// Errors of the transaction are set on incr too
+		c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.SetNX(ctx, key, 0, window)
+			incr = p.Incr(ctx, key)
+			return nil
+		})
+		return incr
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+const (
+	// eventsChannel is the Redis pub/sub channel events are published on
+	eventsChannel = "envelope:events"
+	// eventsLog is the Redis stream events are appended to, So clients can replay the events they missed
+	eventsLog = "envelope:events:log"
+	// Approximate number of events retained in eventsLog
+	eventsRetained = 1000
+)
+
+// AppendEvent appends an encoded event to the events log and returns it's ID.
+// Older events are trimmed, Only about the last eventsRetained events are kept.
+func (d *DB) AppendEvent(ctx context.Context, payload []byte) (string, error) {
+	var add *redis.StringCmd
+	err := d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		add = c.XAdd(ctx, &redis.XAddArgs{
+			Stream: eventsLog,
+			MaxLen: eventsRetained,
+			Approx: true,
+			ID:     "*",
+			Values: map[string]interface{}{"event": payload},
+		})
+		return add
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return add.Val(), nil
+}
+
+// EventsAfter calls fn with every event appended to the events log after event id, In order.
+// ErrEventsTrimmed is returned if id is not in the events log anymore, Some events after it may be lost.
+func (d *DB) EventsAfter(ctx context.Context, id string, fn func(id string, payload []byte) error) error {
+
+	// XRANGE is inclusive, The first message is id itself if it's still retained
+	var xrange *redis.XMessageSliceCmd
+	err := d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		xrange = c.XRange(ctx, eventsLog, id, "+")
+		return xrange
+	})
+	if err != nil {
+		return err
+	}
+
+	msgs := xrange.Val()
+
+	if len(msgs) == 0 || msgs[0].ID != id {
+		return ErrEventsTrimmed
+	}
+
+	for _, m := range msgs[1:] {
+		payload, _ := m.Values["event"].(string)
+
+		if err := fn(m.ID, []byte(payload)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishEvent publishes an encoded event to every instance of the application
+func (d *DB) PublishEvent(ctx context.Context, payload []byte) error {
+	return d.redis(ctx, func(ctx context.Context, c *redis.Client) redis.Cmder {
+		return c.Publish(ctx, eventsChannel, payload)
+	})
+}
+
+// SubscribeEvents subscribes to events published by every instance of the application.
+// The subscription is re-established by the Redis client if the connection breaks.
+func (d *DB) SubscribeEvents() (<-chan []byte, func() error) {
+	ps := d.Redis.Subscribe(context.Background(), eventsChannel)
+
+	events := make(chan []byte)
+	go func() {
+		defer close(events)
+		for m := range ps.Channel() {
+			events <- []byte(m.Payload)
+		}
+	}()
+
+	return events, ps.Close
 }
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/log"
+	"github.com/lib/pq"
+)
+
+// SeedOptions describes the fake data generated by Seed
+type SeedOptions struct {
+	Posts int
+	// Number of devices posts, likes and comments are spread over
+	Devices int
+	// Posts are spread over this period before now
+	Period time.Duration
+	// Average number of likes and comments on a post, A few popular posts get many times more than the rest
+	Likes    float64
+	Comments float64
+	// Fraction of posts that get reported
+	ReportRatio float64
+	// Seed of the random generator, The same seed generates the same data
+	Seed int64
+}
+
+// seedBatch is the number of posts inserted by one statement
+const seedBatch = 1000
+
+// Seed generates posts, likes, comments and reports that look like real usage, For load testing and checking query plans.
+// Engagement follows a long tail, Most posts get little and a few get a lot.
+// Every generated deviceid starts with seed-, So generated data can be told apart from real data.
+func (d *DB) Seed(ctx context.Context, o SeedOptions) error {
+	log.Infof("Seeding %d posts from %d devices", o.Posts, o.Devices)
+
+	r := rand.New(rand.NewSource(o.Seed))
+	now := time.Now()
+
+	for done := 0; done < o.Posts; done += seedBatch {
+		n := seedBatch
+		if o.Posts-done < n {
+			n = o.Posts - done
+		}
+
+		devices, texts, times := make([]string, n), make([]string, n), make([]time.Time, n)
+		for i := range texts {
+			devices[i] = seedDevice(r, o.Devices)
+			texts[i] = seedPostText(r)
+			times[i] = now.Add(-time.Duration(r.Int63n(int64(o.Period) + 1)))
+		}
+
+		rows, err := d.queryContext(ctx, "INSERT INTO posts(deviceid, post, timestamp, ipaddr) SELECT d, p, t, '127.0.0.1' FROM unnest($1::varchar[], $2::varchar[], $3::timestamptz[]) AS s(d, p, t) RETURNING postid, timestamp",
+			pq.Array(devices), pq.Array(texts), pq.Array(times))
+		if err != nil {
+			return err
+		}
+
+		var postids []int
+		var created []time.Time
+		for rows.Next() {
+			var id int
+			var t time.Time
+			if err := rows.Scan(&id, &t); err != nil {
+				rows.Close()
+				return err
+			}
+			postids = append(postids, id)
+			created = append(created, t)
+		}
+		rows.Close()
+
+		if err := d.seedEngagement(ctx, r, o, postids, created, now); err != nil {
+			return err
+		}
+
+		log.Infof("Seeded %d of %d posts", done+n, o.Posts)
+	}
+
+	// Refresh planner statistics, Otherwise plans are made for the tables as they were before seeding
+	_, err := d.execContext(ctx, "ANALYZE posts, likes, comments, reports")
+	return err
+}
+
+// seedEngagement generates likes, comments and reports on posts created at the times in created
+func (d *DB) seedEngagement(ctx context.Context, r *rand.Rand, o SeedOptions, postids []int, created []time.Time, now time.Time) error {
+	var likePosts, commentPosts, reportPosts []int
+	var likeDevices, commentDevices, comments, reportDevices, reasons []string
+	var commentTimes []time.Time
+
+	for i, postid := range postids {
+		// Likes are distinct devices, So there can't be more of them than devices
+		likes := seedCount(r, o.Likes)
+		if likes > o.Devices {
+			likes = o.Devices
+		}
+		first := r.Intn(o.Devices)
+		for l := 0; l < likes; l++ {
+			likePosts = append(likePosts, postid)
+			likeDevices = append(likeDevices, fmt.Sprintf("seed-%d", (first+l)%o.Devices))
+		}
+
+		for c := seedCount(r, o.Comments); c > 0; c-- {
+			commentPosts = append(commentPosts, postid)
+			commentDevices = append(commentDevices, seedDevice(r, o.Devices))
+			comments = append(comments, seedCommentText(r))
+			// Comments come after the post, Most of them soon after it
+			after := time.Duration(r.ExpFloat64() * float64(time.Hour))
+			if t := created[i].Add(after); t.Before(now) {
+				commentTimes = append(commentTimes, t)
+			} else {
+				commentTimes = append(commentTimes, now)
+			}
+		}
+
+		if r.Float64() < o.ReportRatio {
+			reportPosts = append(reportPosts, postid)
+			reportDevices = append(reportDevices, seedDevice(r, o.Devices))
+			reasons = append(reasons, seedReportReasons[r.Intn(len(seedReportReasons))])
+		}
+	}
+
+	_, err := d.execContext(ctx, "INSERT INTO likes(postid, deviceid) SELECT * FROM unnest($1::integer[], $2::varchar[]) ON CONFLICT DO NOTHING",
+		pq.Array(likePosts), pq.Array(likeDevices))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.execContext(ctx, "INSERT INTO comments(postid, deviceid, comment, timestamp) SELECT * FROM unnest($1::integer[], $2::varchar[], $3::varchar[], $4::timestamptz[])",
+		pq.Array(commentPosts), pq.Array(commentDevices), pq.Array(comments), pq.Array(commentTimes))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.execContext(ctx, "INSERT INTO reports(postid, deviceid, reason) SELECT * FROM unnest($1::integer[], $2::varchar[], $3::varchar[])",
+		pq.Array(reportPosts), pq.Array(reportDevices), pq.Array(reasons))
+	return err
+}
+
+// seedCount returns a count with mean close to mean from a long tailed distribution
+func seedCount(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+
+	// Squaring an exponential makes the tail longer, Dividing by 2 keeps the mean
+	e := r.ExpFloat64()
+	return int(e * e * mean / 2)
+}
+
+// seedDevice returns one of n generated deviceids, Some devices are a lot more active than others
+func seedDevice(r *rand.Rand, n int) string {
+	i := int(float64(n) * r.Float64() * r.Float64())
+	return fmt.Sprintf("seed-%d", i)
+}
+
+var (
+	seedOpenings = []string{
+		"Honestly", "Does anyone else feel like", "Unpopular opinion:", "Just saw", "Can we talk about how",
+		"Confession:", "Not gonna lie,", "Today I realised", "Somebody please explain why", "Hot take:",
+		"Reminder that", "Whoever", "Finally", "Is it just me or", "PSA:",
+	}
+	seedSubjects = []string{
+		"the canteen samosas", "the library wifi", "the 8 AM lecture", "the hostel mess", "my roommate",
+		"the professor in room 204", "the rain this week", "the exam schedule", "the fest this year",
+		"the new parking rules", "the power cut last night", "the bus to Dehradun", "the placement season",
+		"whoever plays guitar at 2 AM", "the stray dog near the gate",
+	}
+	seedPredicates = []string{
+		"is the best thing about this place", "needs to be talked about more", "ruined my whole day",
+		"made me smile today", "is completely overrated", "deserves an award", "is getting out of hand",
+		"is the reason I'm still sane", "has been like this for years", "should be illegal",
+		"is actually kind of wholesome", "won't stop being a problem",
+	}
+	seedEndings = []string{"", "", "", " 😂", " 🙃", " lol", " Thoughts?", " Fight me.", " Just saying.", " #relatable", "!!!", " 😭"}
+
+	seedReplies = []string{
+		"This is so true", "Hard disagree", "Finally someone said it", "lmao", "Who is this 👀",
+		"Same here", "Not this again", "Underrated post", "Go touch some grass", "Couldn't agree more",
+		"Wait really?", "I thought it was just me", "Take my upvote", "Bro what", "This made my day",
+	}
+
+	seedReportReasons = []string{"spam", "harassment", "offensive language", "shares personal information", "off topic"}
+)
+
+// seedPostText returns text of a post, Sometimes a few sentences long
+func seedPostText(r *rand.Rand) string {
+	sentences := 1 + seedCount(r, 1)
+	if sentences > 5 {
+		sentences = 5
+	}
+
+	parts := make([]string, sentences)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%s %s %s.", seedOpenings[r.Intn(len(seedOpenings))], seedSubjects[r.Intn(len(seedSubjects))], seedPredicates[r.Intn(len(seedPredicates))])
+	}
+
+	return strings.Join(parts, " ") + seedEndings[r.Intn(len(seedEndings))]
+}
+
+// seedCommentText returns text of a comment
+func seedCommentText(r *rand.Rand) string {
+	return seedReplies[r.Intn(len(seedReplies))] + seedEndings[r.Intn(len(seedEndings))]
+}
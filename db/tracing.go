@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ishanjain28/envelope-backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Every SQL statement run while serving a request is run with these, So each gets a span that is a child of the request's span.
+// Statements are recorded with their placeholders, Arguments are never recorded since they carry deviceids.
+
+func (d *DB) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	defer span.End()
+
+	// The span covers execution of the query, Not reading of the rows
+	rows, err := d.Pq.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+func (d *DB) queryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSQLSpan(ctx, query)
+	defer span.End()
+
+	row := d.Pq.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != sql.ErrNoRows {
+		recordError(span, err)
+	}
+	return row
+}
+
+func (d *DB) execContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	defer span.End()
+
+	res, err := d.Pq.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return res, err
+}
+
+// startSQLSpan starts a span named by the operation of query, e.g. SELECT
+func startSQLSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	op := "SQL"
+	if f := strings.Fields(query); len(f) > 0 {
+		op = strings.ToUpper(f[0])
+	}
+
+	return tracing.Tracer().Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", op),
+		attribute.String("db.statement", query),
+	))
+}
+
+// startRedisSpan starts a span of a Redis command, It's named once the command is known.
+// Arguments of commands are never recorded, Keys are deviceids.
+func startRedisSpan(ctx context.Context) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "redis", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "redis"),
+	))
+}
+
+// endRedisSpan names span by cmd and ends it
+func endRedisSpan(span trace.Span, cmd redis.Cmder) {
+	name := strings.ToUpper(cmd.Name())
+	span.SetName(name)
+	span.SetAttributes(attribute.String("db.operation", name))
+
+	// redis.Nil is a missing key, Not an error
+	if err := cmd.Err(); err != redis.Nil {
+		recordError(span, err)
+	}
+	span.End()
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -1,14 +0,0 @@
-define({
-  "name": "",
-  "version": "0.0.0",
-  "description": "",
-  "sampleUrl": false,
-  "defaultVersion": "0.0.0",
-  "apidoc": "0.3.0",
-  "generator": {
-    "name": "apidoc",
-    "time": "2018-04-13T17:41:47.284Z",
-    "url": "http://apidocjs.com",
-    "version": "0.17.6"
-  }
-});
@@ -1,27 +1,242 @@
+// Package log is a leveled logger with structured fields.
+//
+// Lines are written as JSON when stdout is not a terminal, So they can be parsed by log collectors.
+// On a terminal they are written in colored human readable form. SetFormat overrides this
+// and SetLevel sets the minimum level of lines that are written, info by default. Both are set from configuration.
 package log
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
+// Level is the severity of a log line
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel returns the Level named s, e.g. info
+func ParseLevel(s string) (Level, error) {
+	for l := LevelDebug; l <= LevelError; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %s", s)
+}
+
+// Fields are key value pairs attached to a log line
+type Fields map[string]interface{}
+
+// Logger writes log lines with a set of fields, The zero value has no fields.
+// It's safe to use a Logger concurrently.
+type Logger struct {
+	fields Fields
+}
+
 var (
-	Info  *log.Logger
-	Warn  *log.Logger
-	Error *log.Logger
+	mu     sync.Mutex
+	level  = LevelInfo
+	asJSON = !stdoutIsTerminal()
+
+	// Every line is written to out, So they can be collected from one stream
+	out io.Writer = os.Stdout
+
+	std = &Logger{}
+
+	prefixes = map[Level]string{
+		LevelDebug: color.CyanString("[DEBUG] "),
+		LevelInfo:  color.GreenString("[INFO] "),
+		LevelWarn:  color.YellowString("[WARN] "),
+		LevelError: color.RedString("[ERROR] "),
+	}
 )
 
-func init() {
-	Info = log.New(os.Stdout,
-		color.GreenString("[INFO] "),
-		log.Ldate|log.Lshortfile)
-	Warn = log.New(os.Stdout,
-		color.YellowString("[WARN] "),
-		log.Ldate|log.Lshortfile)
-
-	Error = log.New(os.Stderr,
-		color.RedString("[ERROR] "),
-		log.Ldate|log.Lshortfile)
+// SetLevel sets the minimum level of lines that are written
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	level = l
+}
+
+func stdoutIsTerminal() bool {
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// SetFormat sets the format of lines to json or text, An empty format decides it by whether stdout is a terminal
+func SetFormat(format string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch format {
+	case "":
+		asJSON = !stdoutIsTerminal()
+	case "json":
+		asJSON = true
+	case "text":
+		asJSON = false
+	default:
+		return fmt.Errorf("unknown log format %s", format)
+	}
+	return nil
+}
+
+// With returns a Logger that adds fields to every line
+func With(fields Fields) *Logger {
+	return std.With(fields)
+}
+
+// With returns a Logger with fields of l and fields, Values in fields replace values of l with same keys
+func (l *Logger) With(fields Fields) *Logger {
+	f := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		f[k] = v
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+
+	return &Logger{fields: f}
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying l
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, Or a Logger without fields if it doesn't carry one.
+// Router puts a Logger with fields of the request in context of every request.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) { l.output(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.output(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.output(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.output(LevelError, format, args...) }
+
+// Fatalf writes a line at LevelError and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.output(LevelError, format, args...)
+	os.Exit(1)
+}
+
+func Debugf(format string, args ...interface{}) { std.output(LevelDebug, format, args...) }
+func Infof(format string, args ...interface{})  { std.output(LevelInfo, format, args...) }
+func Warnf(format string, args ...interface{})  { std.output(LevelWarn, format, args...) }
+func Errorf(format string, args ...interface{}) { std.output(LevelError, format, args...) }
+
+// Fatalf writes a line at LevelError and exits
+func Fatalf(format string, args ...interface{}) {
+	std.output(LevelError, format, args...)
+	os.Exit(1)
+}
+
+// output writes a line, It must be called directly by the exported functions so the caller is found correctly
+func (l *Logger) output(lvl Level, format string, args ...interface{}) {
+	mu.Lock()
+	min, jsonFormat := level, asJSON
+	mu.Unlock()
+
+	if lvl < min {
+		return
+	}
+
+	now := time.Now()
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	if jsonFormat {
+		b.WriteString(`{"time":`)
+		writeJSON(&b, now.Format(time.RFC3339Nano))
+		b.WriteString(`,"level":`)
+		writeJSON(&b, lvl.String())
+		b.WriteString(`,"msg":`)
+		writeJSON(&b, msg)
+		b.WriteString(`,"caller":`)
+		writeJSON(&b, caller)
+		for _, k := range keys {
+			b.WriteString(",")
+			writeJSON(&b, k)
+			b.WriteString(":")
+			writeJSON(&b, l.fields[k])
+		}
+		b.WriteString("}\n")
+	} else {
+		b.WriteString(prefixes[lvl])
+		b.WriteString(now.Format("2006/01/02 15:04:05 "))
+		b.WriteString(caller)
+		b.WriteString(": ")
+		b.WriteString(msg)
+		for _, k := range keys {
+			fmt.Fprintf(&b, " %s=%v", k, l.fields[k])
+		}
+		b.WriteString("\n")
+	}
+
+	// Lines from concurrent requests must not interleave
+	mu.Lock()
+	io.WriteString(out, b.String())
+	mu.Unlock()
+}
+
+// writeJSON writes v encoded as JSON, Values that can't be encoded are written as strings
+func writeJSON(b *strings.Builder, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+
+	enc, err := json.Marshal(v)
+	if err != nil {
+		enc, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(enc)
 }
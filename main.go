@@ -1,34 +1,195 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ishanjain28/envelope-backend/certs"
+	"github.com/ishanjain28/envelope-backend/config"
 	"github.com/ishanjain28/envelope-backend/db"
 	"github.com/ishanjain28/envelope-backend/log"
 	"github.com/ishanjain28/envelope-backend/router"
+	"github.com/ishanjain28/envelope-backend/tracing"
 )
 
-var port = os.Getenv("PORT")
-
 func main() {
-	log.Info.Printf("Starting Envelope Backend...\n")
 
-	if port == "" {
-		log.Error.Fatalln("$PORT not set")
+	// Commands are run with the binary's first argument, e.g. envelope-backend recount. It serves when there is none
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
+	if err := serve(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+// serve serves the API until it receives SIGTERM or SIGINT and then shuts down gracefully.
+// It returns an error when the server can't start or stops serving, After connections to the databases are closed.
+func serve(fs *flag.FlagSet, args []string) error {
+	printConfig := fs.Bool("print-config", false, "print the configuration with secrets redacted and exit")
+	cfg := loadConfig(fs, args)
+
+	if *printConfig {
+		return cfg.Print(os.Stdout)
+	}
+
+	log.Infof("Starting Envelope Backend...")
+
+	err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("error in setting up tracing: %s", err)
 	}
+	// Spans are exported on every return from here on, Including when connecting to the databases fails
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := tracing.Shutdown(ctx); err != nil {
+			log.Warnf("Error in exporting spans: %s", err)
+		}
+	}()
 
-	dbs, err := db.Init()
+	dbs, err := db.Init(cfg)
 	if err != nil {
-		log.Error.Fatalf("%v: %s", err, err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           hsts(router.Init(dbs, cfg), cfg.TLS),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	srv.RegisterOnShutdown(func() {
+		if err := router.CloseStreams(); err != nil {
+			log.Warnf("Error in closing streams: %s", err)
+		}
+	})
+	servers := []*http.Server{srv}
+
+	if cfg.TLS.Enabled() {
+		reloader, err := certs.Load(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			dbs.Close()
+			return fmt.Errorf("error in loading TLS certificate: %s", err)
+		}
+		go reloader.Watch(ctx, cfg.TLS.WatchInterval)
+
+		srv.TLSConfig = tlsConfig(reloader)
+
+		if cfg.TLS.RedirectPort > 0 {
+			servers = append(servers, &http.Server{
+				Addr:              fmt.Sprintf(":%d", cfg.TLS.RedirectPort),
+				Handler:           redirectToHTTPS(cfg.Server.Port),
+				ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+				ReadTimeout:       cfg.Server.ReadTimeout,
+				WriteTimeout:      cfg.Server.WriteTimeout,
+				IdleTimeout:       cfg.Server.IdleTimeout,
+			})
+		}
+	}
+
+	if cfg.Server.MetricsAddress != "" {
+		servers = append(servers, &http.Server{
+			Addr:              cfg.Server.MetricsAddress,
+			Handler:           router.MetricsHandler(),
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		})
+	}
+
+	serveErr := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *http.Server) {
+			if s.TLSConfig != nil {
+				// The certificate is served by TLSConfig
+				serveErr <- s.ListenAndServeTLS("", "")
+				return
+			}
+			serveErr <- s.ListenAndServe()
+		}(s)
+	}
+
+	// A server that fails, e.g. because it's port is taken, Shuts down the others
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
 	}
 
-	router := router.Init(dbs)
+	// A second signal kills the process without waiting for shutdown
+	stop()
 
-	err = http.ListenAndServe(fmt.Sprintf(":%s", port), router)
+	shutdown(servers, dbs, cfg.Server)
+	return err
+}
 
+// loadConfig loads the configuration and applies it's settings of logging, It exits when the configuration is invalid
+func loadConfig(fs *flag.FlagSet, args []string) *config.Config {
+	cfg, err := config.Load(fs, args)
 	if err != nil {
-		log.Error.Fatalln("%v: %s", err, err)
+		log.Fatalf("%s", err)
+	}
+
+	// Both are validated by Load
+	level, _ := log.ParseLevel(cfg.Log.Level)
+	log.SetLevel(level)
+	log.SetFormat(cfg.Log.Format)
+
+	return cfg
+}
+
+// shutdown stops the server gracefully. It reports not ready, Waits for the drain delay, Stops accepting connections,
+// Waits for requests in flight to finish and closes connections to the databases, All within the shutdown timeout.
+// Spans are exported by serve after it returns.
+func shutdown(servers []*http.Server, dbs db.IDB, c config.Server) {
+	log.Infof("Shutting down, Waiting up to %s for requests in flight", c.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+
+	router.Drain()
+
+	select {
+	case <-time.After(c.DrainDelay):
+	case <-ctx.Done():
+	}
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Warnf("Error in draining requests: %s", err)
+		}
 	}
+
+	// Close waits for queries that are still running, e.g. ones abandoned by requests that were cut off
+	closed := make(chan error, 1)
+	go func() {
+		closed <- dbs.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			log.Warnf("Error in closing database connections: %s", err)
+		}
+	case <-ctx.Done():
+		log.Warnf("Timed out closing database connections")
+	}
+
+	log.Infof("Shut down")
 }
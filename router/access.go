@@ -0,0 +1,111 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// maxRequestIDLength is the maximum length of an X-Request-ID accepted from a proxy
+const maxRequestIDLength = 128
+
+// trustedProxies are networks of proxies that are trusted to set X-Request-ID, It's set by Init from configuration
+var trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses IP addresses and CIDR networks, e.g. 10.0.0.0/8 and 127.0.0.1
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+
+	for _, p := range proxies {
+
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %s", p)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// requestID returns X-Request-ID of r if it came from a trusted proxy, So lines of the proxy and ours can be matched.
+// A new ID is generated for every other request.
+func requestID(r *http.Request) string {
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || !validRequestID(id) || !fromTrustedProxy(r) {
+		return RandomString(16)
+	}
+
+	return id
+}
+
+// fromTrustedProxy reports whether the connection of r was made by a trusted proxy
+func fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validRequestID reports whether id is safe to be logged and echoed in headers.
+// UUIDs and most other formats of IDs used by proxies are valid.
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("-_.:+/=", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// accessLog logs a line with the response sent to request r, It's called by Handle when the request ends
+func (rc *RouterContext) accessLog(w *responseWriter, r *http.Request, start time.Time) {
+	fields := log.Fields{
+		"method":     r.Method,
+		"status":     w.status,
+		"bytes":      w.bytes,
+		"latency_ms": time.Since(start).Milliseconds(),
+	}
+	if w.errorCode != "" {
+		fields["error_code"] = w.errorCode
+	}
+
+	rc.log().With(fields).Infof("%s %s %d", r.Method, r.URL.Path, w.status)
+}
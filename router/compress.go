@@ -0,0 +1,221 @@
+package router
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Responses are compressed with brotli or gzip when the client accepts them, Brotli is preferred.
+// Streams are never compressed, Their events have to reach clients as soon as they are written.
+
+// compressMinBytes is the size below which responses are sent uncompressed, Compressing them saves less than it costs
+const compressMinBytes = 1024
+
+// encoder compresses responses with a content coding
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders are the supported content codings, In order of preference.
+// Encoders are pooled, Creating them allocates a lot.
+var encoders = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }}},
+	{"gzip", &sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}},
+}
+
+// negotiateEncoding returns the supported encoding the client prefers in Accept-Encoding,
+// Or an empty string if it accepts none of them.
+func negotiateEncoding(acceptEncoding string) string {
+	// q-value of each coding, * applies to the ones not listed
+	q := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		value := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				value = f
+			}
+		}
+		q[coding] = value
+	}
+
+	best, bestQ := "", 0.0
+	for _, e := range encoders {
+		value, ok := q[e.name]
+		if !ok {
+			value = q["*"]
+		}
+
+		if value > bestQ {
+			best, bestQ = e.name, value
+		}
+	}
+
+	return best
+}
+
+// compressWriter compresses the response written to it with encoding.
+// Writes are buffered until compressMinBytes have been written, Smaller responses are sent as they are.
+// Responses that are already encoded, e.g. by promhttp, Or that are not text are never compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	// Status code written by the handler, It's sent once it's decided whether to compress
+	status int
+	buf    []byte
+	// Whether the response is being written as it is
+	passthrough bool
+	// Encoder the response is written to when it's being compressed
+	enc encoder
+}
+
+// newCompressWriter returns a compressWriter writing to w when the client of r accepts a supported encoding,
+// Otherwise w is returned. The response varies by Accept-Encoding either way.
+func newCompressWriter(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return w
+	}
+
+	return &compressWriter{ResponseWriter: w, encoding: encoding}
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+
+	// Responses without a body and responses that are already encoded are sent right away
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified || !w.compressible() {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// compressible reports whether the response is text that has not been encoded
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	ct := h.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/json") || strings.HasPrefix(ct, "text/")
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < compressMinBytes {
+		return len(b), nil
+	}
+
+	if err := w.startCompressing(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// startCompressing sends the header and writes what has been buffered to the encoder
+func (w *compressWriter) startCompressing() error {
+	for _, e := range encoders {
+		if e.name == w.encoding {
+			w.enc = e.pool.Get().(encoder)
+		}
+	}
+	w.enc.Reset(w.ResponseWriter)
+
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	_, err := w.enc.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// sendBuffered sends what has been buffered as it is, Everything written after it is sent as it is too
+func (w *compressWriter) sendBuffered() error {
+	w.passthrough = true
+	if w.status == 0 {
+		return nil
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Flush sends whatever has been written so far, A response that is still small is not compressed
+func (w *compressWriter) Flush() {
+	switch {
+	case w.enc != nil:
+		w.enc.Flush()
+	case !w.passthrough:
+		w.sendBuffered()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response, It must be called once the handler is done.
+func (w *compressWriter) Close() error {
+	if w.enc == nil {
+		if w.passthrough {
+			return nil
+		}
+		return w.sendBuffered()
+	}
+
+	err := w.enc.Close()
+
+	// Don't keep the response alive through the pool
+	w.enc.Reset(nil)
+	for _, e := range encoders {
+		if e.name == w.encoding {
+			e.pool.Put(w.enc)
+		}
+	}
+	w.enc = nil
+
+	return err
+}
+
+// Unwrap returns the underlying ResponseWriter, It's used by http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,5 +1,14 @@
 package router
 
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ishanjain28/envelope-backend/db"
+)
+
+// Error codes sent to the client in ErrorCode field of HTTPError
 var (
 
 	// ErrInvalidData is sent when a value in request is invalid
@@ -12,6 +21,8 @@ var (
 	ErrOutOfValidRegion = "OUT_OF_REGION"
 	// ErrNotRegistered is sent when a deviceid is not registered
 	ErrNotRegistered = "NOT_REGISTERED"
+	// ErrBanned is sent when a banned device tries to register
+	ErrBanned = "BANNED"
 	// ErrNotFound is sent when a expected value is missing from request
 	ErrNotFound = "NOT_FOUND"
 
@@ -19,4 +30,107 @@ var (
 	ErrTimeout = "TIMEOUT"
 
 	ErrExpired = "EXPIRED"
+
+	// ErrAlreadyLiked is sent when a device likes a post it has already liked
+	ErrAlreadyLiked = "ALREADY_LIKED"
+
+	// ErrNotOwner is sent when a device modifies a post submitted by some other device
+	ErrNotOwner = "NOT_OWNER"
+	// ErrPostNotFound is sent when a requested post doesn't exist
+	ErrPostNotFound = "POST_NOT_FOUND"
+
+	// ErrTooLarge is sent when body of a request is larger than the configured limit
+	ErrTooLarge = "TOO_LARGE"
+	// ErrUnsupportedMediaType is sent when body of a request is neither JSON nor form encoded
+	ErrUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+
+	// ErrRouteNotFound is sent when no route matches path of a request
+	ErrRouteNotFound = "ROUTE_NOT_FOUND"
+	// ErrMethodNotAllowed is sent when a route matches path of a request but not it's method
+	ErrMethodNotAllowed = "METHOD_NOT_ALLOWED"
+
+	// ErrRateLimited is sent when a device has made too many requests of a kind, Retry-After tells when to try again
+	ErrRateLimited = "RATE_LIMITED"
 )
+
+// errorStatus is the status code sent along with each ErrorCode
+var errorStatus = map[string]int{
+	ErrInvalidData:          http.StatusBadRequest,
+	ErrInternal:             http.StatusInternalServerError,
+	ErrParsing:              http.StatusBadRequest,
+	ErrOutOfValidRegion:     http.StatusUnauthorized,
+	ErrNotRegistered:        http.StatusUnauthorized,
+	ErrBanned:               http.StatusForbidden,
+	ErrNotFound:             http.StatusBadRequest,
+	ErrTimeout:              http.StatusRequestTimeout,
+	ErrExpired:              http.StatusBadRequest,
+	ErrAlreadyLiked:         http.StatusConflict,
+	ErrNotOwner:             http.StatusForbidden,
+	ErrPostNotFound:         http.StatusNotFound,
+	ErrTooLarge:             http.StatusRequestEntityTooLarge,
+	ErrUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	ErrRouteNotFound:        http.StatusNotFound,
+	ErrMethodNotAllowed:     http.StatusMethodNotAllowed,
+	ErrRateLimited:          http.StatusTooManyRequests,
+}
+
+// errorMap maps errors returned by db operations to the ErrorCode sent to the client.
+// Errors are matched with errors.Is, In order.
+var errorMap = []struct {
+	err       error
+	errorCode string
+}{
+	{db.ErrNotRegistered, ErrNotRegistered},
+	{db.ErrInvalidPostID, ErrInvalidData},
+	{db.ErrAlreadyLiked, ErrAlreadyLiked},
+	{db.ErrNotOwner, ErrNotOwner},
+}
+
+// handleError converts an error returned by a db operation to an *HTTPError.
+// Errors in errorMap are the fault of the user and are sent as Level 1 errors,
+// Everything else is a Level 3 internal error.
+func handleError(rc *RouterContext, err error) *HTTPError {
+
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr
+	}
+
+	for _, m := range errorMap {
+		if errors.Is(err, m.err) {
+			return &HTTPError{
+				IError:          err,
+				Level:           LevelClient,
+				ErrorCode:       m.errorCode,
+				GenericResponse: HTTPResponse(errorStatus[m.errorCode]),
+			}
+		}
+	}
+
+	// Drivers may abort a query with an error of their own when rc.ctx is done, So check the context as well
+	if errors.Is(err, context.Canceled) || rc.ctx.Err() == context.Canceled {
+		// Client has gone away and won't read the response, Nothing went wrong on our side
+		return &HTTPError{
+			IError:          err,
+			Level:           LevelClient,
+			ErrorCode:       ErrTimeout,
+			GenericResponse: HTTPResponse(http.StatusRequestTimeout),
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || rc.ctx.Err() == context.DeadlineExceeded {
+		return &HTTPError{
+			IError:          err,
+			Level:           LevelInternal,
+			ErrorCode:       ErrTimeout,
+			GenericResponse: HTTPResponse(http.StatusRequestTimeout),
+		}
+	}
+
+	return &HTTPError{
+		IError:          err,
+		Level:           LevelInternal,
+		ErrorCode:       ErrInternal,
+		GenericResponse: HTTPResponse(http.StatusInternalServerError),
+	}
+}
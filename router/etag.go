@@ -0,0 +1,70 @@
+package router
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/ishanjain28/envelope-backend/db"
+)
+
+// ETags let clients polling the feed revalidate what they already have and get 304 Not Modified when nothing changed.
+// They are weak, The same posts are sent with different encodings, See compress.go.
+
+// feedETag returns the ETag of a page of posts. It's derived from the newest post in the page
+// and versions of every post in it, So new posts, Edits, Likes, Comments and deleted posts all change it.
+func feedETag(posts []*db.Post) string {
+	h := fnv.New64a()
+
+	newest := 0
+	for i, p := range posts {
+		fmt.Fprintf(h, "%d:%d,", p.ID, p.Version)
+
+		if i == 0 || p.CreatedAt.After(posts[newest].CreatedAt) {
+			newest = i
+		}
+	}
+
+	newestID := 0
+	if len(posts) > 0 {
+		newestID = posts[newest].ID
+	}
+
+	return fmt.Sprintf(`W/"%d-%x"`, newestID, h.Sum64())
+}
+
+// postETag returns the ETag of a post along with it's comments, Adding a comment bumps the version of the post
+func postETag(p *db.Post) string {
+	return fmt.Sprintf(`W/"%d-%d"`, p.ID, p.Version)
+}
+
+// notModified sets etag on the response and reports whether the client already has it,
+// In which case 304 Not Modified has been sent and nothing else must be written.
+//
+// Responses depend on the device that requested them, e.g. editable. So they may only be kept by the client
+// and it has to revalidate them every time. Either outcome is counted in conditionalResponses.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		observeConditional(r, false)
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	observeConditional(r, true)
+	return true
+}
+
+// etagMatches reports whether If-None-Match header matches etag, ETags are compared weakly
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
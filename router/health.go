@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readinessTimeout is the timeout of /readyz, Dependencies that don't respond by then are not ready
+const readinessTimeout = 2 * time.Second
+
+// draining is set when the server starts shutting down, /readyz reports not ready from then on
+// So load balancers stop sending new requests while in-flight ones finish.
+var draining int32
+
+// Drain marks the server as shutting down, It can't be undone
+func Drain() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// Draining reports whether Drain has been called
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// serveLiveness responds as long as the process can serve requests, Dependencies are not checked.
+// A process that fails this should be restarted.
+func serveLiveness() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		Send(HTTPResponse(http.StatusOK), w)
+		return nil
+	}
+}
+
+// serveReadiness checks every dependency and responds with 200 when all of them are healthy, 503 otherwise.
+// Errors of checks are logged but not sent, They can carry addresses of the dependencies.
+func serveReadiness() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		resp := ReadinessResponse{Ready: true, Checks: map[string]DependencyStatus{}}
+
+		if Draining() {
+			resp.Ready, resp.Draining = false, true
+		} else {
+			for _, c := range rc.db.Health(rc.ctx) {
+				s := DependencyStatus{OK: c.Err == nil, LatencyMS: c.Latency.Milliseconds()}
+				if c.Err != nil {
+					resp.Ready = false
+					rc.log().Warnf("readiness check of %s failed: %s", c.Name, c.Err)
+				}
+				resp.Checks[c.Name] = s
+			}
+		}
+
+		status := http.StatusOK
+		if !resp.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		resp.GenericResponse = HTTPResponse(status)
+
+		w.WriteHeader(status)
+		Send(resp, w)
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics is the registry of every metric served by MetricsHandler.
+// Labels must never carry deviceids or anything else that identifies a device, Routes are labelled with their templates.
+//
+// There is no cache in front of the feed, The only cache is the one of clients revalidating with ETags.
+// It's hit ratio is exported by conditionalResponses.
+var metrics = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "envelope",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of requests by route, status and error_code.",
+	}, []string{"route", "method", "status", "error_code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "envelope",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests by route and error_code. Streams are not observed.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "error_code"})
+
+	conditionalResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "envelope",
+		Subsystem: "http",
+		Name:      "conditional_responses_total",
+		Help:      "Responses of feeds and posts carrying an ETag by route and result, not_modified when the client already had the response and full otherwise.",
+	}, []string{"route", "result"})
+
+	postsSubmitted = businessCounter("posts_submitted_total", "Number of posts submitted.")
+	likesTotal     = businessCounter("likes_total", "Number of likes on posts.")
+	commentsTotal  = businessCounter("comments_total", "Number of comments on posts.")
+	reportsTotal   = businessCounter("reports_total", "Number of reports of posts.")
+	registrations  = businessCounter("registrations_total", "Number of devices registered.")
+)
+
+func businessCounter(name, help string) prometheus.Counter {
+	return prometheus.NewCounter(prometheus.CounterOpts{Namespace: "envelope", Name: name, Help: help})
+}
+
+func init() {
+	metrics.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		conditionalResponses,
+		postsSubmitted,
+		likesTotal,
+		commentsTotal,
+		reportsTotal,
+		registrations,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "envelope",
+			Name:      "panics_total",
+			Help:      "Number of panics recovered in handlers.",
+		}, func() float64 { return float64(Panics()) }),
+	)
+}
+
+// observeRequest records a finished request in the metrics.
+// Latency of streams is the time the client stayed connected, So it's not observed.
+func observeRequest(route string, r *http.Request, w *responseWriter, latency time.Duration, streaming bool) {
+	requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(w.status), w.errorCode).Inc()
+
+	if !streaming {
+		requestDuration.WithLabelValues(route, r.Method, w.errorCode).Observe(latency.Seconds())
+	}
+}
+
+// observeConditional records whether a response carrying an ETag was sent in full or as 304 Not Modified
+func observeConditional(r *http.Request, notModified bool) {
+	route := r.URL.Path
+	if cr := mux.CurrentRoute(r); cr != nil {
+		if t, err := cr.GetPathTemplate(); err == nil {
+			route = t
+		}
+	}
+
+	result := "full"
+	if notModified {
+		result = "not_modified"
+	}
+	conditionalResponses.WithLabelValues(route, result).Inc()
+}
+
+// MetricsHandler serves metrics in Prometheus text format at /metrics.
+// It's served on the admin address of configuration, Not along with the API. Scrapes are not logged or counted.
+func MetricsHandler() http.Handler {
+	m := http.NewServeMux()
+	m.Handle("/metrics", promhttp.HandlerFor(metrics, promhttp.HandlerOpts{}))
+	return m
+}
@@ -1,38 +1,154 @@
 package router
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 )
 
+// SubmitPostRequest is the body of a request to submit a post
+type SubmitPostRequest struct {
+	Post string `json:"post"`
+}
+
+// EditPostRequest is the body of a request to edit a post
+type EditPostRequest struct {
+	PostID json.Number `json:"postid"`
+	Post   string      `json:"post"`
+}
+
+// LikePostRequest is the body of a request to like a post
+type LikePostRequest struct {
+	PostID json.Number `json:"postid"`
+}
+
+// ReportRequest is the body of a request to report a post
+type ReportRequest struct {
+	PostID json.Number `json:"postid"`
+	Reason string      `json:"reason"`
+}
+
+// CommentRequest is the body of a request to comment on a post
+type CommentRequest struct {
+	PostID  json.Number `json:"postid"`
+	Comment string      `json:"comment"`
+}
+
+// Request bodies of the v1 API, postid is a part of the path in these requests
+
+// UpdatePostRequest is the body of a request to edit a post
+type UpdatePostRequest struct {
+	Post string `json:"post"`
+}
+
+// CreateCommentRequest is the body of a request to comment on a post
+type CreateCommentRequest struct {
+	Comment string `json:"comment"`
+}
+
+// CreateReportRequest is the body of a request to report a post
+type CreateReportRequest struct {
+	Reason string `json:"reason"`
+}
+
 type SubmitPostResponse struct {
-	PostID    int   `json:"postid"`
-	Timestamp int64 `json:"timestamp"`
-	likes     int   `json:"likes"`
+	PostID    int       `json:"postid"`
+	Timestamp int64     `json:"timestamp"`
+	CreatedAt time.Time `json:"created_at"`
+	likes     int       `json:"likes"`
+	GenericResponse
+}
+
+type SubmitCommentResponse struct {
+	CommentID int       `json:"commentid"`
+	Timestamp int64     `json:"timestamp"`
+	CreatedAt time.Time `json:"created_at"`
 	GenericResponse
 }
 
+// Envelope is the response to every successful request to the v1 API.
+// Errors are sent as HTTPError, That carries the same status and status_code fields.
+type Envelope struct {
+	Data interface{} `json:"data"`
+	GenericResponse
+}
+
+// DeviceResponse is sent in Envelope after registering a device
+type DeviceResponse struct {
+	Hash string `json:"hash"`
+}
+
+// LikesResponse is sent in Envelope after liking a post
+type LikesResponse struct {
+	LikesCount int `json:"likes_count"`
+}
+
 type GenericResponse struct {
 	Status string `json:"status"`
 	Code   int    `json:"status_code"`
 }
 
+// ReadinessResponse is sent by /readyz
+type ReadinessResponse struct {
+	Ready bool `json:"ready"`
+	// Whether the server is shutting down, Dependencies are not checked then
+	Draining bool                        `json:"draining"`
+	Checks   map[string]DependencyStatus `json:"checks"`
+	GenericResponse
+}
+
+// DependencyStatus is the result of checking a dependency, e.g. postgres
+type DependencyStatus struct {
+	OK        bool  `json:"ok"`
+	LatencyMS int64 `json:"latency_ms"`
+}
+
 type RegisterDeviceResponse struct {
 	Hash string `json:"hash"`
 	GenericResponse
 }
 
+// ErrorLevel decides what Handle does with an HTTPError returned by a Handler
+type ErrorLevel int
+
+const (
+	// LevelClient errors are the fault of the client, e.g. a bad request. There is no advantage in logging them,
+	// The error is sent to the client and the remaining handlers are not executed.
+	LevelClient ErrorLevel = iota + 1
+	// LevelWarn errors are warnings, Something that might be important to the server.
+	// They are logged and the request moves on to the next handler, Nothing is sent to the client.
+	LevelWarn
+	// LevelInternal errors are failures on our side. They are reported to the ErrorReporter,
+	// The error is sent to the client and the remaining handlers are not executed.
+	// An HTTPError without a Level is treated as LevelInternal.
+	LevelInternal
+)
+
+func (l ErrorLevel) String() string {
+	switch l {
+	case LevelClient:
+		return "client"
+	case LevelWarn:
+		return "warn"
+	case LevelInternal:
+		return "internal"
+	}
+	return fmt.Sprintf("ErrorLevel(%d)", int(l))
+}
+
 // HTTPError is returned by middlewares
 // This is used internally by the application
 // and some fields are serialized and sent as error response to the request.
 type HTTPError struct {
 	// Error Level, Used Internally
-	Level int `json:"-"`
+	Level ErrorLevel `json:"-"`
 	//Error message that's logged to console
 	IError error `json:"-"`
-	// Device ID of request Origin
-	deviceid string `json:"-"`
 	// Short Error Code that can be used by client to pinpoint exact error
 	ErrorCode string `json:"error_code"`
+	// Problems with individual fields of the request, If the request was invalid
+	Fields []FieldError `json:"fields,omitempty"`
 
 	GenericResponse
 }
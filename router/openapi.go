@@ -0,0 +1,290 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPI is the OpenAPI 3 document describing every route, It's generated by Init
+var openAPI map[string]interface{}
+
+// serveOpenAPI sends the OpenAPI document
+func serveOpenAPI() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		Send(openAPI, w)
+		return nil
+	}
+}
+
+// generateOpenAPI generates an OpenAPI 3 document from description of routes
+func generateOpenAPI(routes []route) map[string]interface{} {
+	g := &schemaGenerator{schemas: map[string]interface{}{}}
+
+	paths := map[string]map[string]interface{}{}
+
+	for _, rt := range routes {
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]interface{}{}
+		}
+
+		op := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+			"tags":        []string{rt.tag},
+			"responses":   g.responses(rt),
+		}
+
+		if rt.description != "" {
+			op["description"] = rt.description
+		}
+
+		if rt.successor != "" {
+			op["deprecated"] = true
+			op["description"] = fmt.Sprintf("Deprecated, Use %s. This route will be removed after %s.", rt.successor, legacySunset.Format("2006-01-02"))
+		}
+
+		params := []interface{}{}
+		for _, p := range rt.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.required,
+				"schema":      map[string]interface{}{"type": p.typ},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.request != nil {
+			schema := g.schema(reflect.TypeOf(rt.request))
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json":                  map[string]interface{}{"schema": schema},
+					"application/x-www-form-urlencoded": map[string]interface{}{"schema": schema},
+				},
+			}
+		}
+
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	g.schema(reflect.TypeOf(HTTPError{}))
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Envelope",
+			"description": "Backend of Envelope, An anonymous posting platform",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+		},
+	}
+}
+
+// responses documents the success response and every error response of a route.
+// Errors are grouped by their status codes.
+func (g *schemaGenerator) responses(rt route) map[string]interface{} {
+
+	var success map[string]interface{}
+	switch {
+	case rt.enveloped:
+		data := map[string]interface{}{"nullable": true}
+		if rt.response != nil {
+			data = g.schema(reflect.TypeOf(rt.response))
+		}
+		success = map[string]interface{}{
+			"allOf": []interface{}{
+				g.schema(reflect.TypeOf(GenericResponse{})),
+				map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"data": data},
+					"required":   []string{"data"},
+				},
+			},
+		}
+
+	case rt.response != nil:
+		success = g.schema(reflect.TypeOf(rt.response))
+
+	case rt.status == http.StatusSwitchingProtocols:
+		// Nothing is sent in the body after switching protocols
+
+	case rt.produces != "":
+		success = map[string]interface{}{"type": "string"}
+
+	default:
+		success = g.schema(reflect.TypeOf(GenericResponse{}))
+	}
+
+	contentType := "application/json"
+	if rt.produces != "" {
+		contentType = rt.produces
+	}
+
+	ok := map[string]interface{}{"description": http.StatusText(rt.status)}
+	if success != nil {
+		ok["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": success},
+		}
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(rt.status): ok}
+
+	if rt.conditional {
+		ok["headers"] = map[string]interface{}{
+			"ETag": map[string]interface{}{
+				"description": "Version of the response, Send it in If-None-Match to revalidate",
+				"schema":      map[string]interface{}{"type": "string"},
+			},
+		}
+		responses[strconv.Itoa(http.StatusNotModified)] = map[string]interface{}{
+			"description": "Not Modified, The ETag in If-None-Match is still current",
+		}
+	}
+
+	byStatus := map[int][]string{}
+	for _, code := range append(append([]string{}, rt.errors...), ErrInternal, ErrTimeout) {
+		status := errorStatus[code]
+		byStatus[status] = append(byStatus[status], code)
+	}
+
+	for status, codes := range byStatus {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": fmt.Sprintf("%s, error_code is one of %s", http.StatusText(status), strings.Join(codes, ", ")),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(HTTPError{}))},
+			},
+		}
+	}
+
+	return responses
+}
+
+// operationID returns a unique name of route, e.g. GET /v1/posts/{id} is getV1PostsId
+func operationID(rt route) string {
+	id := strings.ToLower(rt.method)
+	for _, part := range strings.FieldsFunc(rt.path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '.'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// schemaGenerator generates JSON schemas of Go types.
+// Named struct types are added to schemas and referenced with $ref.
+type schemaGenerator struct {
+	schemas map[string]interface{}
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	numberType = reflect.TypeOf(json.Number(""))
+)
+
+// schema returns JSON schema of t, That is how encoding/json serializes a value of type t
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == numberType:
+		return map[string]interface{}{"type": "integer"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+
+		if _, ok := g.schemas[t.Name()]; ok {
+			return ref
+		}
+
+		// Set a placeholder before generating properties, So recursive types terminate
+		g.schemas[t.Name()] = map[string]interface{}{}
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		g.properties(t, properties, &required)
+		sort.Strings(required)
+
+		s := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		g.schemas[t.Name()] = s
+
+		return ref
+	}
+
+	return map[string]interface{}{}
+}
+
+// properties adds all the fields of struct t that are serialized by encoding/json to properties.
+// Fields of embedded structs are promoted, Fields without omitempty are required.
+func (g *schemaGenerator) properties(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		if f.Anonymous && tag[0] == "" {
+			g.properties(f.Type, properties, required)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = g.schema(f.Type)
+
+		omitempty := false
+		for _, o := range tag[1:] {
+			omitempty = omitempty || o == "omitempty"
+		}
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
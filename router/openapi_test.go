@@ -0,0 +1,87 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ishanjain28/envelope-backend/config"
+	"github.com/ishanjain28/envelope-backend/db"
+)
+
+// stubDB is an IDB that's only good enough to call Init, Every method but SubscribeEvents panics
+type stubDB struct {
+	db.IDB
+}
+
+func (stubDB) SubscribeEvents() (<-chan []byte, func() error) {
+	events := make(chan []byte)
+	return events, func() error {
+		close(events)
+		return nil
+	}
+}
+
+// TestDocumented checks every route registered by Init against the OpenAPI document served at /openapi.json
+// and every operation in the document against the registered routes.
+func TestDocumented(t *testing.T) {
+	r := Init(stubDB{}, config.Default())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d, want 200", w.Code)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("error in decoding /openapi.json: %s", err)
+	}
+
+	// METHOD path of every registered route
+	registered := map[string]bool{}
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("route %s has no methods: %s", path, err)
+			return nil
+		}
+
+		for _, m := range methods {
+			registered[m+" "+path] = true
+
+			if _, ok := doc.Paths[path][strings.ToLower(m)]; !ok {
+				t.Errorf("route %s %s is not documented", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range doc.Paths {
+		for m := range ops {
+			if !registered[strings.ToUpper(m)+" "+path] {
+				t.Errorf("%s %s is documented but not registered", strings.ToUpper(m), path)
+			}
+		}
+	}
+
+	// Legacy routes are registered without the /v1 prefix, Make sure the walk has seen them
+	for _, rt := range legacyRoutes() {
+		if !registered[rt.method+" "+rt.path] {
+			t.Errorf("legacy route %s %s is not registered", rt.method, rt.path)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/common"
+	"github.com/ishanjain28/envelope-backend/db"
+	"github.com/ishanjain28/envelope-backend/log"
+	"github.com/ishanjain28/envelope-backend/stream"
+)
+
+// Operations shared by the v1 API and the legacy routes.
+// An operation validates it's input, Performs the operation on behalf of rc.deviceid and returns the result.
+// Reading input from the request and sending the response in right shape is left to the handlers.
+
+// register registers rc.deviceid and returns the hash the device has to verify itself with
+func (rc *RouterContext) register(r *http.Request) (string, *HTTPError) {
+
+	// TODO: Add Context
+	region, err := common.GetRegionofIP(common.GetIPAddr(r))
+	if err != nil {
+		return "", &HTTPError{
+			ErrorCode:       ErrInternal,
+			Level:           LevelInternal,
+			GenericResponse: HTTPResponse(http.StatusInternalServerError),
+			IError:          err,
+		}
+	}
+
+	if !allowedRegion(region) {
+		return "", &HTTPError{
+			ErrorCode:       ErrOutOfValidRegion,
+			Level:           LevelClient,
+			GenericResponse: HTTPResponse(http.StatusUnauthorized),
+			IError:          fmt.Errorf("%s: device is from %s", ErrOutOfValidRegion, region),
+		}
+	}
+
+	banned, err := rc.db.IsBanned(rc.ctx, rc.deviceid)
+	if err != nil {
+		return "", handleError(rc, err)
+	}
+
+	if banned {
+		return "", &HTTPError{
+			ErrorCode:       ErrBanned,
+			Level:           LevelClient,
+			GenericResponse: HTTPResponse(http.StatusForbidden),
+			IError:          fmt.Errorf("%s: device %s is banned", ErrBanned, common.DeviceHash(rc.deviceid)),
+		}
+	}
+
+	h := RandomString(20)
+
+	// TODO: Set correct expiry time here
+	err = rc.db.RegisterDeviceID(rc.ctx, rc.deviceid, h, 0)
+	if err != nil {
+		return "", handleError(rc, err)
+	}
+
+	registrations.Inc()
+
+	return h, nil
+}
+
+// verify checks that rc.deviceid is registered with hash h
+func (rc *RouterContext) verify(h string) *HTTPError {
+
+	v := &validator{}
+	v.required("hash", h)
+
+	if e := v.error(); e != nil {
+		return e
+	}
+
+	hash, err := rc.db.VerifyDeviceID(rc.ctx, rc.deviceid)
+	if err != nil {
+		return handleError(rc, err)
+	}
+
+	if hash != h {
+		return &HTTPError{
+			ErrorCode:       ErrExpired,
+			GenericResponse: HTTPResponse(http.StatusBadRequest),
+			Level:           LevelClient,
+		}
+	}
+
+	return nil
+}
+
+// latestPosts returns the most recent limit posts
+func (rc *RouterContext) latestPosts(limit int) ([]*db.Post, *HTTPError) {
+
+	posts, err := rc.db.FetchNPosts(rc.ctx, limit)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	return rc.withMeta(posts), nil
+}
+
+// postsFrom returns limit posts created before or after(prop) the post postid
+func (rc *RouterContext) postsFrom(postid, limit int, prop string) ([]*db.Post, *HTTPError) {
+
+	posts, err := rc.db.FetchPostsFromID(rc.ctx, postid, limit, prop)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	// FetchPostsFromID returns nil when postid doesn't exist
+	if posts == nil {
+		posts = []*db.Post{}
+	}
+
+	return rc.withMeta(posts), nil
+}
+
+// post returns a post along with all it's comments
+func (rc *RouterContext) post(postid string) (*db.Post, *HTTPError) {
+
+	v := &validator{}
+	v.integer("postid", postid)
+
+	if e := v.error(); e != nil {
+		return nil, e
+	}
+
+	p, err := rc.db.FetchPost(rc.ctx, postid)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	if p == nil {
+		return nil, &HTTPError{
+			Level:           LevelClient,
+			ErrorCode:       ErrPostNotFound,
+			GenericResponse: HTTPResponse(http.StatusNotFound),
+		}
+	}
+
+	p.Comments, err = rc.db.FetchPostComments(rc.ctx, postid)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	return rc.withMeta([]*db.Post{p})[0], nil
+}
+
+// createPost submits a new post with text
+func (rc *RouterContext) createPost(r *http.Request, text string) (*db.Post, *HTTPError) {
+
+	v := &validator{}
+
+	if v.required("post", text) {
+		v.maxLength("post", text, maxPostLength)
+	}
+
+	if e := v.error(); e != nil {
+		return nil, e
+	}
+
+	p := &db.Post{
+		DeviceID:  rc.deviceid,
+		CreatedAt: time.Now(),
+		Text:      text,
+		IPAddr:    fetchRemoteIpAddr(common.GetIPAddr(r)),
+	}
+
+	// p.ID is set in SubmitPost after retrieving ID of post inserted in database
+	err := rc.db.SubmitPost(rc.ctx, p)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	postsSubmitted.Inc()
+	rc.publishPost(stream.PostCreated, p)
+
+	return rc.withMeta([]*db.Post{p})[0], nil
+}
+
+// updatePost replaces text of post postid, Only the device that submitted it can edit a post
+func (rc *RouterContext) updatePost(postid, text string) (*db.Post, *HTTPError) {
+
+	v := &validator{}
+
+	v.integer("postid", postid)
+
+	if v.required("post", text) {
+		v.maxLength("post", text, maxPostLength)
+	}
+
+	if e := v.error(); e != nil {
+		return nil, e
+	}
+
+	p, err := rc.db.EditPost(rc.ctx, postid, rc.deviceid, text)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	rc.publishPost(stream.PostUpdated, p)
+
+	return rc.withMeta([]*db.Post{p})[0], nil
+}
+
+// like likes post postid and returns total likes on it
+func (rc *RouterContext) like(postid string) (int, *HTTPError) {
+
+	v := &validator{}
+	id := v.integer("postid", postid)
+
+	if e := v.error(); e != nil {
+		return 0, e
+	}
+
+	likes, err := rc.db.LikePost(rc.ctx, postid, rc.deviceid)
+	if err != nil {
+		return 0, handleError(rc, err)
+	}
+
+	likesTotal.Inc()
+	rc.publish(stream.LikeCount, id, LikesResponse{LikesCount: likes})
+
+	return likes, nil
+}
+
+// comments returns all the comments on post postid
+func (rc *RouterContext) comments(postid string) ([]*db.Comment, *HTTPError) {
+
+	v := &validator{}
+	v.integer("postid", postid)
+
+	if e := v.error(); e != nil {
+		return nil, e
+	}
+
+	c, err := rc.db.FetchPostComments(rc.ctx, postid)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	return c, nil
+}
+
+// createComment puts a comment with text on post postid
+func (rc *RouterContext) createComment(postid, text string) (*db.Comment, *HTTPError) {
+
+	v := &validator{}
+
+	id := v.integer("postid", postid)
+
+	if v.required("comment", text) {
+		v.maxLength("comment", text, maxCommentLength)
+	}
+
+	if e := v.error(); e != nil {
+		return nil, e
+	}
+
+	c := &db.Comment{
+		DeviceID:  rc.deviceid,
+		CreatedAt: time.Now(),
+		Text:      text,
+	}
+
+	err := rc.db.Comment(rc.ctx, postid, c)
+	if err != nil {
+		return nil, handleError(rc, err)
+	}
+
+	commentsTotal.Inc()
+	rc.publish(stream.CommentCreated, id, c)
+
+	return c, nil
+}
+
+// reportPost reports post postid for reason
+func (rc *RouterContext) reportPost(postid, reason string) *HTTPError {
+
+	v := &validator{}
+
+	id := v.integer("postid", postid)
+
+	if v.required("reason", reason) {
+		v.maxLength("reason", reason, maxReasonLength)
+	}
+
+	if e := v.error(); e != nil {
+		return e
+	}
+
+	n, err := rc.db.Report(rc.ctx, postid, rc.deviceid, reason)
+	if err != nil {
+		return handleError(rc, err)
+	}
+
+	reportsTotal.Inc()
+
+	// Only the report that reaches a threshold acts on it, Later reports don't repeat it
+	switch {
+	case n == moderation.FlagReports:
+		rc.log().With(log.Fields{"postid": id, "reports": n}).Warnf("post needs review")
+	case n == moderation.HideReports:
+		// The post is left out of feeds from now on, Connected clients remove it like a deleted post
+		rc.log().With(log.Fields{"postid": id, "reports": n}).Warnf("post hidden from feeds")
+		rc.publish(stream.PostDeleted, id, nil)
+	}
+
+	return nil
+}
+
+// withMeta sets fields of PostMeta that depend on the device making the request
+func (rc *RouterContext) withMeta(posts []*db.Post) []*db.Post {
+	for _, p := range posts {
+		p.Editable = p.DeviceID == rc.deviceid
+	}
+	return posts
+}
+
+// publish sends an event to clients connected to the stream.
+// Failing to publish is logged and doesn't fail the request, The change has already been saved.
+// So the event is published even if the client goes away right after the change, rc.ctx is not used.
+func (rc *RouterContext) publish(typ string, postid int, data interface{}) {
+	if events == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	err := events.Publish(ctx, &stream.Event{Type: typ, PostID: postid, Data: data})
+	if err != nil {
+		rc.log().Warnf("error in publishing %s event: %s", typ, err)
+	}
+}
+
+// publishPost publishes an event carrying p, Fields specific to the device that made the change are left out
+func (rc *RouterContext) publishPost(typ string, p *db.Post) {
+	post := *p
+	post.Editable = false
+	post.Comments = nil
+
+	rc.publish(typ, p.ID, &post)
+}
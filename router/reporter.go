@@ -0,0 +1,110 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// ErrorReport describes an error that happened while serving a request
+type ErrorReport struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// Hash of deviceid of the device that made the request, Raw deviceids are never reported
+	Device    string `json:"device,omitempty"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	ErrorCode string `json:"error_code"`
+	Error     string `json:"error"`
+	// Stack trace of the goroutine, Set when a handler panics
+	Stack string `json:"stack,omitempty"`
+}
+
+// ErrorReporter receives errors that need attention of a developer.
+// Report is called concurrently from all the requests.
+type ErrorReporter interface {
+	Report(r *ErrorReport)
+}
+
+// reporter receives panics and LevelInternal errors of every request, It's set by Init from $ERROR_REPORT_FILE
+var reporter ErrorReporter = NewStdoutReporter()
+
+// panics is the number of panics recovered in Handle
+var panics uint64
+
+// Panics returns the number of panics recovered in Handle since the application started
+func Panics() uint64 {
+	return atomic.LoadUint64(&panics)
+}
+
+// StdoutReporter writes reports to stdout as error lines of the log, In the same format as every other line
+type StdoutReporter struct{}
+
+// NewStdoutReporter returns a StdoutReporter
+func NewStdoutReporter() *StdoutReporter {
+	return &StdoutReporter{}
+}
+
+// Report logs r along with it's stack trace
+func (s *StdoutReporter) Report(r *ErrorReport) {
+	fields := log.Fields{
+		"request_id": r.RequestID,
+		"method":     r.Method,
+		"path":       r.Path,
+		"status":     r.Status,
+		"error_code": r.ErrorCode,
+	}
+	if r.Device != "" {
+		fields["device"] = r.Device
+	}
+	if r.Stack != "" {
+		fields["stack"] = r.Stack
+	}
+
+	log.With(fields).Errorf("%s", r.Error)
+}
+
+// FileReporter appends reports to a file, One JSON object per line
+type FileReporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileReporter opens file at path for appending reports, It's created if it doesn't exist
+func NewFileReporter(path string) (*FileReporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileReporter{f: f}, nil
+}
+
+// Report appends r to the file.
+// Reports that can't be written are printed to stderr, So they are not lost.
+func (fr *FileReporter) Report(r *ErrorReport) {
+	b, err := json.Marshal(r)
+	if err == nil {
+		fr.mu.Lock()
+		_, err = fr.f.Write(append(b, '\n'))
+		fr.mu.Unlock()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error in writing error report: %s, %s: %s\n%s", err, r.RequestID, r.Error, r.Stack)
+	}
+}
+
+// Close closes the file
+func (fr *FileReporter) Close() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.f.Close()
+}
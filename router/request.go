@@ -0,0 +1,116 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// decodeRequest reads body of the request into v, Which must be a pointer to a request struct.
+// application/json bodies are decoded with encoding/json and fields not in v are rejected.
+// Form encoded bodies are read into the string fields of v, Named by their json tags.
+// Bodies larger than maxBodyBytes are rejected in both cases.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) *HTTPError {
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	ct := r.Header.Get("Content-Type")
+	if ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return handleBodyError(err)
+		}
+		ct = mt
+	}
+
+	switch ct {
+	case "application/json":
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+
+		err := dec.Decode(v)
+		if err != nil {
+			return handleBodyError(err)
+		}
+
+		// Body must contain a single JSON value
+		if dec.More() {
+			return handleBodyError(errors.New("body must contain a single JSON object"))
+		}
+
+		return nil
+
+	case "", "application/x-www-form-urlencoded":
+		err := r.ParseForm()
+		if err != nil {
+			return handleBodyError(err)
+		}
+
+		rv := reflect.ValueOf(v).Elem()
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" || rv.Field(i).Kind() != reflect.String {
+				continue
+			}
+			rv.Field(i).SetString(r.Form.Get(name))
+		}
+
+		return nil
+
+	default:
+		return &HTTPError{
+			Level:           LevelClient,
+			IError:          fmt.Errorf("unsupported Content-Type %s", ct),
+			ErrorCode:       ErrUnsupportedMediaType,
+			GenericResponse: HTTPResponse(http.StatusUnsupportedMediaType),
+		}
+	}
+}
+
+// handleBodyError converts an error in reading or decoding body of a request to *HTTPError
+func handleBodyError(err error) *HTTPError {
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &HTTPError{
+			Level:           LevelClient,
+			IError:          err,
+			ErrorCode:       ErrTooLarge,
+			GenericResponse: HTTPResponse(http.StatusRequestEntityTooLarge),
+		}
+	}
+
+	v := &validator{}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		typ := typeErr.Type.Kind().String()
+		if typeErr.Type == reflect.TypeOf(json.Number("")) {
+			typ = "number"
+		}
+		v.add(typeErr.Field, ErrInvalidData, fmt.Sprintf("%s must be a %s", typeErr.Field, typ))
+
+	// encoding/json doesn't have a type for this error
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		v.add(field, ErrInvalidData, fmt.Sprintf("%s is not a known field", field))
+
+	default:
+		return &HTTPError{
+			Level:           LevelClient,
+			IError:          err,
+			ErrorCode:       ErrParsing,
+			GenericResponse: HTTPResponse(http.StatusBadRequest),
+		}
+	}
+
+	e := v.error()
+	e.IError = err
+	return e
+}
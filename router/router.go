@@ -2,22 +2,53 @@ package router
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/ishanjain28/envelope-backend/common"
+	"github.com/ishanjain28/envelope-backend/config"
 	"github.com/ishanjain28/envelope-backend/db"
 	"github.com/ishanjain28/envelope-backend/log"
+	"github.com/ishanjain28/envelope-backend/stream"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const (
+	letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// Maximum number of posts that can be fetched in one request
+	maxFetchLimit = 100
+	// Maximum length of a post in characters
+	maxPostLength = 5000
+	// Maximum length of reason of a report in characters
+	maxReasonLength = 500
+	// Maximum length of a comment in characters
+	maxCommentLength = 2000
+)
 
 var (
-	workingRegion = "Uttarakhand"
+	// regions devices can register from, Devices from any region can register when it's empty
+	regions []string
+
+	// Legacy routes are deprecated since legacyDeprecation and will be removed after legacySunset
+	legacyDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacySunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+	// maxBodyBytes is the maximum size of a request body, It's set by Init from configuration
+	maxBodyBytes int64 = 64 << 10
+
+	// defaultTimeout is the timeout of routes that don't set one, It's set by Init from configuration
+	defaultTimeout = 5 * time.Second
+
+	// rateLimits and moderation are set by Init from configuration, Nothing is limited or moderated until then
+	rateLimits config.RateLimit
+	moderation config.Moderation
 )
 
 // RouterContext holds all the connections/information a request will need
@@ -25,230 +56,279 @@ type RouterContext struct {
 	db       db.IDB
 	deviceid string
 	ctx      context.Context
+	// requestid identifies the request in logs and error reports, It's sent in X-Request-ID header of the response
+	requestid string
 }
 
+// unmatchedRoute is the route of requests no route matched in logs, metrics and traces
+const unmatchedRoute = "unmatched"
+
 // Handler interface provides for a easy, convenient middleware pattern
 type Handler func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError
 
 // Handle executes all the Handlers one by one.
-// Error from an handler is evaluated after it's execution and depending on the level of error
-// The decision to execute next middleware is taken.
-// Currently, There are 3 levels of errors.
-// Level 1,2 and 3. i
-// Level 1 errors are Bad requests, Or anything that is just the fault of user and there is no advantage in logging them
-// Level 2 errors are warnings, Something that might be important to the server. These errors are logged to console but the request is moved forward to next middleware.
-// Level 3, All hell broke loose, Log the request and send an appropriate error response to the user, Don't forward request to next middleware
-func Handle(pqre db.IDB, handlers ...Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// Error from an handler is evaluated after it's execution and depending on it's ErrorLevel
+// The decision to execute next middleware is taken, See ErrorLevel for what happens at each level.
+//
+// Every request gets exactly one response. If the chain ends without writing anything, That is a bug in the chain,
+// It's reported and the client gets a 500. An error returned after a response has been written is not sent.
+//
+// Context of the request is cancelled when the client goes away or after timeout, Whichever happens first.
+// A timeout <= 0 never times out, That is for routes that stream responses.
+//
+// A panic in a handler is recovered and reported to reporter with it's stack trace, The client gets a 500.
+//
+// Responses of routes that don't stream are compressed when the client accepts it, See compress.go.
+//
+// Every request is traced, See startRequestSpan.
+func Handle(pqre db.IDB, timeout time.Duration, handlers ...Handler) http.Handler {
+	// Names of spans of the handlers
+	names := make([]string, len(handlers))
+	for i, h := range handlers {
+		names[i] = handlerName(h)
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 
-		// context for redis is set here and provided with first arguments in relevant functions
-		//pqre.Redis = pqre.Redis.WithContext(ctx)
+		start := time.Now()
+
+		// Paths of requests no route matched are left out, So they can't add a label to metrics each
+		route := unmatchedRoute
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if t, err := cr.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+
+		span, r := startRequestSpan(r, route)
+
+		ctx, cancel := r.Context(), context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		defer cancel()
 
 		rc := &RouterContext{
-			db:  pqre,
-			ctx: ctx,
+			db:        pqre,
+			requestid: requestID(r),
 		}
 
-		w.Header().Add("Content-Type", "application/json")
+		// Lines logged with context of the request carry these fields, That includes the lines logged in db
+		fields := log.Fields{
+			"request_id": rc.requestid,
+			"route":      route,
+		}
+		if sc := span.SpanContext(); sc.IsSampled() {
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		rc.ctx = log.NewContext(ctx, log.With(fields))
+
+		// Streams are never compressed
+		out := rw
+		if timeout > 0 {
+			out = newCompressWriter(rw, r)
+		}
+
+		w := &responseWriter{ResponseWriter: out}
+		w.Header().Set("X-Request-ID", rc.requestid)
 
-		for _, handler := range handlers {
-			e := handler(rc, w, r)
-
-			if e != nil {
-				switch e.Level {
-				case 1:
-					w.WriteHeader(e.Code)
-					err := json.NewEncoder(w).Encode(e)
-					if err != nil {
-						w.Header().Set("Content-Type", "text/plain")
-						w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
-					}
-					return
-
-				case 2:
-					if e.deviceid != "" {
-						log.Warn.Printf("[%s] %s\n", e.deviceid, e.IError)
-					} else {
-						log.Warn.Println(e.IError)
-					}
-
-				case 3:
-					if e.deviceid != "" {
-						log.Error.Printf("[%s] %s\n", e.deviceid, e.IError)
-					} else {
-						log.Warn.Println(e.IError)
-					}
-
-					if e.IError == context.DeadlineExceeded {
-						e.Code = http.StatusRequestTimeout
-						e.ErrorCode = ErrTimeout
-					}
-
-					w.WriteHeader(e.Code)
-					err := json.NewEncoder(w).Encode(e)
-					if err != nil {
-						log.Error.Printf("%s\n", err, err)
-						w.Header().Set("Content-Type", "text/plain")
-						w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
-					}
-					return
+		defer func() {
+			if cw, ok := out.(*compressWriter); ok {
+				if err := cw.Close(); err != nil {
+					rc.log().Debugf("error in finishing compressed response: %s", err)
 				}
 			}
+
+			rc.accessLog(w, r, start)
+			observeRequest(route, r, w, time.Since(start), timeout <= 0)
+			endRequestSpan(span, w)
+		}()
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			// net/http aborts the response silently on this panic, Let it do that
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			atomic.AddUint64(&panics, 1)
+
+			stack := string(debug.Stack())
+
+			rc.sendError(w, &HTTPError{
+				ErrorCode:       ErrInternal,
+				Level:           LevelInternal,
+				GenericResponse: HTTPResponse(http.StatusInternalServerError),
+			})
+			reporter.Report(rc.errorReport(r, w, fmt.Sprintf("panic: %v", v), stack))
+		}()
+
+		w.Header().Add("Content-Type", "application/json")
+
+		for i, handler := range handlers {
+			e := rc.runHandler(names[i], handler, w, r)
+			if e == nil {
+				continue
+			}
+
+			switch e.Level {
+			case LevelClient:
+				rc.sendError(w, e)
+				return
+
+			case LevelWarn:
+				rc.log().Warnf("%v", e.IError)
+
+			default:
+				rc.sendError(w, e)
+				reporter.Report(rc.errorReport(r, w, fmt.Sprint(e.IError), ""))
+				return
+			}
+		}
+
+		if !w.written() {
+			rc.sendError(w, &HTTPError{
+				ErrorCode:       ErrInternal,
+				Level:           LevelInternal,
+				GenericResponse: HTTPResponse(http.StatusInternalServerError),
+			})
+			reporter.Report(rc.errorReport(r, w, "handler chain ended without writing a response", ""))
 		}
 	})
 }
 
-func Init(pqre db.IDB) *mux.Router {
-	r := mux.NewRouter()
+// log returns the Logger carrying fields of the request
+func (rc *RouterContext) log() *log.Logger {
+	return log.FromContext(rc.ctx)
+}
 
-	/**
-	 * @api {post} /register-device Register a Device
-	 * @apiName RegisterDevice
-	 * @apiGroup Device
-	 *
-	 * @apiHeader {String} deviceid Unique Device ID
-	 *
-	 *
-	 * @apiSuccessExample {json} Success-Example:
-	 *		HTTP/1.1 200 Ok
-	 * 		{"hash":"XVlBzgbaiCMRAjWwhTHc","status":"OK","status_code":200}
-	 *
-	 * @apiErrorExample {json} Error-Example:
-	 *		HTTP/1.1 400 Bad Request
-	 * 		{"error_code":"NOT_FOUND","status":"Bad Request","status_code":400}
-	 *
-	 *		HTTP/1.1 500 Internal Server Error
-	 *		{"error_code":"INTERNAL_ERROR","status":"Internal Server Error","status_code:"500"}
-	 *
-	 *		HTTP/1.1 401 Unauthorized
-	 *		{"error_code":"OUT_OF_REGION","status":"Unauthorized","status_code:"401"}
-	 */
-	r.Handle("/register-device", Handle(pqre,
-		parseDeviceID(),
-		registerDevice(),
-	)).Methods("POST")
-
-	/**
-	 * @api {get} /verify-device Verify a Device
-	 * @apiName VerifyDevice
-	 * @apiGroup Device
-	 *
-	 * @apiHeader {String} deviceid Unique Device ID
-	 *
-	 *
-	 * @apiSuccessExample {json} Success-Example:
-	 *		HTTP/1.1 200 Ok
-	 * 		{"status":"OK","status_code":200}
-	 *
-	 * @apiErrorExample {json} Error-Example:
-	 *		HTTP/1.1 400 Bad Request
-	 * 		{"error_code":"EXPIRED","status":"Bad Request","status_code":400}
-	 *
-	 *		HTTP/1.1 500 Internal Server Error
-	 *		{"error_code":"INTERNAL_ERROR","status":"Internal Server Error","status_code:"500"}
-	 *
-	 *		HTTP/1.1 401 Unauthorized
-	 *		{"error_code":"NOT_REGISTERED","status":"Unauthorized","status_code:"401"}
-	 */
-	r.Handle("/verify-device", Handle(pqre,
-		parseDeviceID(),
-		verifyDevice(),
-	)).Methods("GET")
-
-	r.Handle("/report", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		parseForm(),
-		report(),
-	)).Methods("POST")
-
-	r.Handle("/submit-post", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		parseForm(),
-		submitPost(),
-	)).Methods("POST")
-
-	r.Handle("/edit-post", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		parseForm(),
-		editPost(),
-	)).Methods("POST")
-
-	r.Handle("/fetch/{tag}", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		fetchPost(),
-	)).Methods("GET")
-
-	r.Handle("/like-post", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		parseForm(),
-		likePost(),
-	)).Methods("POST")
-
-	r.Handle("/comment", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-		parseForm(),
-	)).Methods("POST")
-
-	r.Handle("/fetch-comments", Handle(pqre,
-		parseDeviceID(),
-		verifyDeviceID(),
-	)).Methods("GET")
+// setDeviceID sets the device making the request, Lines logged after this carry a hash of deviceid.
+// Raw deviceids are never logged.
+func (rc *RouterContext) setDeviceID(deviceid string) {
+	rc.deviceid = deviceid
+	rc.ctx = log.NewContext(rc.ctx, rc.log().With(log.Fields{"device": common.DeviceHash(deviceid)}))
+}
 
-	return r
+// sendError sends e to the client, Unless a response has already been written.
+// Internal errors without an ErrorCode are sent as ErrInternal.
+func (rc *RouterContext) sendError(w *responseWriter, e *HTTPError) {
+	if w.written() {
+		rc.log().Warnf("response already written, Dropping %s error %s: %v", e.Level, e.ErrorCode, e.IError)
+		return
+	}
+
+	if e.Code == 0 {
+		e.GenericResponse = HTTPResponse(http.StatusInternalServerError)
+	}
+	if e.ErrorCode == "" && e.Level != LevelClient {
+		e.ErrorCode = ErrInternal
+	}
+
+	w.errorCode = e.ErrorCode
+	w.WriteHeader(e.Code)
+	err := json.NewEncoder(w).Encode(e)
+	if err != nil {
+		rc.log().Errorf("error in encoding error response: %s", err)
+	}
 }
 
-// registerDevice receives a deviceid via POST and puts it in redis for 2 months, And sends a Hash back in response
-func registerDevice() Handler {
-	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+// errorReport returns an ErrorReport of the request r, After the error response has been written to w
+func (rc *RouterContext) errorReport(r *http.Request, w *responseWriter, err, stack string) *ErrorReport {
+	report := &ErrorReport{
+		Time:      time.Now(),
+		RequestID: rc.requestid,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    w.status,
+		ErrorCode: w.errorCode,
+		Error:     err,
+		Stack:     stack,
+	}
+	if rc.deviceid != "" {
+		report.Device = common.DeviceHash(rc.deviceid)
+	}
+
+	return report
+}
+
+// Init registers every route and sets up the router from configuration c
+func Init(pqre db.IDB, c *config.Config) *mux.Router {
+	r := mux.NewRouter()
+
+	maxBodyBytes = c.API.MaxBodyBytes
+	defaultTimeout = c.API.RequestTimeout
+	regions = c.API.Regions
+	rateLimits = c.RateLimit
+	moderation = c.Moderation
+
+	// Statistics of connection pools are exported when the database provides them
+	if c, ok := pqre.(interface{ Collectors() []prometheus.Collector }); ok {
+		metrics.MustRegister(c.Collectors()...)
+	}
 
-		// TODO: Add Context
-		_, err := common.GetRegionofIP(common.GetIPAddr(r))
+	// Events published by any instance are received through Redis pub/sub and sent to clients connected to /v1/stream
+	events = stream.NewHub(pqre)
+	go events.Run()
+
+	if path := c.API.ErrorReportFile; path != "" {
+		fr, err := NewFileReporter(path)
 		if err != nil {
-			return &HTTPError{
-				ErrorCode:       ErrInternal,
-				Level:           3,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-				IError:          err,
-			}
+			log.Warnf("Error in opening error report file %s, Reporting errors to stdout: %s", path, err)
+		} else {
+			reporter = fr
 		}
+	}
 
-		//		if region != workingRegion {
-		//			return &HTTPError{
-		//				ErrorCode: ErrOutOfValidRegion,
-		//				deviceid:  rc.deviceid,
-		//				IError:    errors.New(fmt.Sprintf("%s: %s is from %s", ErrOutOfValidRegion, common.GetIPAddr(r), region)),
-		//				Level:     3,
-		//				Status:    http.StatusUnauthorized,
-		//			}
-		//		}
+	nets, err := parseTrustedProxies(c.API.TrustedProxies)
+	if err != nil {
+		log.Warnf("Invalid trusted proxies, X-Request-ID from proxies is not used: %s", err)
+	} else {
+		trustedProxies = nets
+	}
 
-		h := RandomString(20)
+	for _, rt := range routes() {
+		handlers := rt.handlers
+		if rt.successor != "" {
+			handlers = append([]Handler{deprecated(rt.successor)}, handlers...)
+		}
 
-		// TODO: Set correct expiry time here
-		err = rc.db.RegisterDeviceID(rc.ctx, rc.deviceid, h, 0)
-		if err != nil {
-			return &HTTPError{
-				IError:          err,
-				Level:           3,
-				deviceid:        rc.deviceid,
-				ErrorCode:       ErrInternal,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-			}
+		timeout := rt.timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+
+		r.Handle(rt.path, Handle(pqre, timeout, handlers...)).Methods(rt.method)
+	}
+
+	// Requests that match no route go through Handle too, So they get an access line and an X-Request-ID like the rest
+	r.NotFoundHandler = Handle(pqre, defaultTimeout, unmatched(ErrRouteNotFound))
+	r.MethodNotAllowedHandler = Handle(pqre, defaultTimeout, unmatched(ErrMethodNotAllowed))
+
+	openAPI = generateOpenAPI(routes())
+
+	return r
+}
+
+// Legacy routes, Each of them is an adapter over the operation used by it's successor in v1 API.
+
+// registerDevice receives a deviceid via POST and puts it in redis for 2 months, And sends a Hash back in response
+func registerDevice() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		h, e := rc.register(r)
+		if e != nil {
+			return e
 		}
 
 		Send(RegisterDeviceResponse{
 			Hash:            h,
 			GenericResponse: HTTPResponse(http.StatusOK),
 		}, w)
+
 		return nil
 	}
 }
@@ -261,37 +341,8 @@ func registerDevice() Handler {
 func verifyDevice() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		h := r.Header.Get("hash")
-		if h == "" {
-			return handleMissingDataError("hash")
-		}
-
-		hash, err := rc.db.VerifyDeviceID(rc.ctx, rc.deviceid)
-		if err != nil {
-
-			if err.Error() == ErrNotRegistered {
-				return &HTTPError{
-					ErrorCode:       ErrNotRegistered,
-					GenericResponse: HTTPResponse(http.StatusUnauthorized),
-					Level:           1,
-				}
-			}
-
-			return &HTTPError{
-				deviceid:        rc.deviceid,
-				ErrorCode:       ErrInternal,
-				IError:          err,
-				Level:           3,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-			}
-		}
-
-		if hash != h {
-			return &HTTPError{
-				ErrorCode:       ErrExpired,
-				GenericResponse: HTTPResponse(http.StatusBadRequest),
-				Level:           1,
-			}
+		if e := rc.verify(r.Header.Get("hash")); e != nil {
+			return e
 		}
 
 		Send(HTTPResponse(http.StatusOK), w)
@@ -303,101 +354,88 @@ func verifyDevice() Handler {
 // Fetch Latest, Fetch After Id, Serves Post, Timestamp, liked
 func fetchPost() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
-		v := mux.Vars(r)
+		v := &validator{}
 
-		l := r.URL.Query().Get("limit")
-		limit, err := strconv.Atoi(l)
-		if limit == 0 || err != nil {
-			limit = 20
-		}
+		limit := legacyLimit(r.URL.Query().Get("limit"))
 
-		tag := v["tag"]
+		tag := mux.Vars(r)["tag"]
 
-		posts := []*db.Post{}
+		var (
+			posts []*db.Post
+			e     *HTTPError
+		)
 
 		// Send Latest Posts
 		if tag == "latest" {
-			posts, err = rc.db.FetchNPosts(rc.ctx, limit)
-			if err != nil {
-				return &HTTPError{
-					Level:           3,
-					deviceid:        rc.deviceid,
-					GenericResponse: HTTPResponse(http.StatusInternalServerError),
-					ErrorCode:       ErrInternal,
-					IError:          err,
-				}
+			if e := v.error(); e != nil {
+				return e
 			}
+
+			posts, e = rc.latestPosts(limit)
 		} else {
 
-			tagInt, err := strconv.Atoi(tag)
-			if err != nil {
-				return handleMissingDataError("postid")
-			}
+			tagInt := v.integer("postid", tag)
 
 			prop := r.URL.Query().Get("prop")
-			if prop == "" || (prop != "before" && prop != "after") {
-				return handleMissingDataError("prop")
+			v.oneOf("prop", prop, "before", "after")
+
+			if e := v.error(); e != nil {
+				return e
 			}
 
 			// Fetch Posts before specified id or after specified id
-			// Send posts that were created after the specified postid
-			// if the postid is invalid, Send a "Bad Request" Response
-			posts, err = rc.db.FetchPostsFromID(rc.ctx, tagInt, limit, prop)
-			if err != nil {
-				return &HTTPError{
-					Level:           3,
-					deviceid:        rc.deviceid,
-					ErrorCode:       ErrInternal,
-					IError:          err,
-					GenericResponse: HTTPResponse(http.StatusInternalServerError),
-				}
-			}
+			posts, e = rc.postsFrom(tagInt, limit, prop)
+		}
+		if e != nil {
+			return e
 		}
+
+		if notModified(w, r, feedETag(posts)) {
+			return nil
+		}
+
 		Send(posts, w)
 
 		return nil
 	}
 }
 
+// legacyLimit parses limit of /fetch the way older clients expect, It's never an error.
+// A missing or invalid limit is 20 and a limit above maxFetchLimit is clamped to it.
+func legacyLimit(value string) int {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 20
+	}
+
+	if limit > maxFetchLimit {
+		return maxFetchLimit
+	}
+	return limit
+}
+
 // IP Address, DeviceID, Post, time, POSTid; Response: Time, POSTid
 func submitPost() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		post := r.Form.Get("post")
-		if post == "" {
-			return handleMissingDataError("post")
+		req := &SubmitPostRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
 		}
 
-		timestamp := time.Now().Unix()
-
-		p := &db.Post{
-			DeviceID:  rc.deviceid,
-			Timestamp: timestamp,
-			Text:      post,
-			IPAddr:    fetchRemoteIpAddr(common.GetIPAddr(r)),
+		p, e := rc.createPost(r, req.Post)
+		if e != nil {
+			return e
 		}
 
-		err := rc.db.SubmitPost(rc.ctx, p)
-
-		if err != nil {
-			return &HTTPError{
-				Level:           3,
-				deviceid:        rc.deviceid,
-				IError:          err,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-				ErrorCode:       ErrInternal,
-			}
-		}
-
-		// p.ID is set in SubmitPost after retrieving ID of post inserted in database
-		resp := &SubmitPostResponse{
+		Send(&SubmitPostResponse{
 			likes:           0,
 			PostID:          p.ID,
-			Timestamp:       timestamp,
+			Timestamp:       p.Timestamp,
+			CreatedAt:       p.CreatedAt,
 			GenericResponse: HTTPResponse(http.StatusOK),
-		}
+		}, w)
 
-		Send(resp, w)
 		return nil
 	}
 }
@@ -406,18 +444,22 @@ func submitPost() Handler {
 func editPost() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		post := r.Form.Get("post")
-		if post == "" {
-			return handleMissingDataError("post")
+		req := &EditPostRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
 		}
 
-		postid := r.Form.Get("postid")
-		if postid == "" {
-			return handleMissingDataError("postid")
+		p, e := rc.updatePost(req.PostID.String(), req.Post)
+		if e != nil {
+			return e
 		}
 
-		//timestamp := time.Now().Unix()
-		//p, err := rc.db.FetchPost(rc.ctx, postid)
+		Send(&SubmitPostResponse{
+			PostID:          p.ID,
+			Timestamp:       p.Timestamp,
+			CreatedAt:       p.CreatedAt,
+			GenericResponse: HTTPResponse(http.StatusOK),
+		}, w)
 
 		return nil
 	}
@@ -427,29 +469,13 @@ func editPost() Handler {
 func likePost() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		postid := r.Form.Get("postid")
-		if postid == "" {
-			return handleMissingDataError("postid")
+		req := &LikePostRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
 		}
 
-		err := rc.db.LikePost(rc.ctx, postid, rc.deviceid)
-		if err != nil {
-
-			if err.Error() == db.ErrInvalidPostID {
-
-				return &HTTPError{
-					ErrorCode:       ErrInvalidData,
-					Level:           1,
-					GenericResponse: HTTPResponse(http.StatusBadRequest),
-				}
-			}
-
-			return &HTTPError{
-				Level:           3,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-				IError:          err,
-				deviceid:        rc.deviceid,
-			}
+		if _, e := rc.like(req.PostID.String()); e != nil {
+			return e
 		}
 
 		Send(HTTPResponse(http.StatusOK), w)
@@ -459,38 +485,56 @@ func likePost() Handler {
 }
 
 // input: Postid, output: Comments object array, Comment, Timestamp,
-func fetchComments() {}
+func fetchComments() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-func submitComment() {}
+		c, e := rc.comments(r.URL.Query().Get("postid"))
+		if e != nil {
+			return e
+		}
 
-// postid, deviceid, reason
-func report() Handler {
+		Send(c, w)
+
+		return nil
+	}
+}
+
+// input: postid, comment; output: commentid, timestamp
+func submitComment() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		postid := r.Form.Get("postid")
-		if postid == "" {
-			return handleMissingDataError("postid")
+		req := &CommentRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
 		}
 
-		reason := r.Form.Get("reason")
-		if reason == "" {
-			return handleMissingDataError("reason")
+		c, e := rc.createComment(req.PostID.String(), req.Comment)
+		if e != nil {
+			return e
 		}
 
-		err := rc.db.Report(rc.ctx, postid, rc.deviceid, reason)
-		if err != nil {
+		Send(&SubmitCommentResponse{
+			CommentID:       c.ID,
+			Timestamp:       c.Timestamp,
+			CreatedAt:       c.CreatedAt,
+			GenericResponse: HTTPResponse(http.StatusOK),
+		}, w)
 
-			if err == sql.ErrNoRows {
-				return handleMissingDataError("postid")
-			}
+		return nil
+	}
+}
 
-			return &HTTPError{
-				IError:          err,
-				ErrorCode:       ErrInternal,
-				deviceid:        rc.deviceid,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
-				Level:           3,
-			}
+// postid, deviceid, reason
+func report() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		req := &ReportRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
+		}
+
+		if e := rc.reportPost(req.PostID.String(), req.Reason); e != nil {
+			return e
 		}
 
 		Send(HTTPResponse(http.StatusOK), w)
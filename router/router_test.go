@@ -0,0 +1,213 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/config"
+)
+
+// reports records what Handle reports instead of logging it
+type reports []*ErrorReport
+
+func (rs *reports) Report(r *ErrorReport) {
+	*rs = append(*rs, r)
+}
+
+// TestHandle checks that every handler chain gets exactly one response, Whatever the handlers do.
+func TestHandle(t *testing.T) {
+	const (
+		ok       = `{"status":"OK","status_code":200}` + "\n"
+		internal = `{"error_code":"INTERNAL_ERROR","status":"Internal Server Error","status_code":500}` + "\n"
+	)
+
+	sendOK := func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		return Send(HTTPResponse(http.StatusOK), w)
+	}
+	fail := func(level ErrorLevel, code int, errorCode string) Handler {
+		return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+			return &HTTPError{
+				Level:           level,
+				IError:          errors.New("handler failed"),
+				ErrorCode:       errorCode,
+				GenericResponse: HTTPResponse(code),
+			}
+		}
+	}
+	doNothing := func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		return nil
+	}
+	panics := func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		panic("handler panicked")
+	}
+
+	tests := []struct {
+		name     string
+		handlers []Handler
+		status   int
+		body     string
+		// Number of errors reported to the ErrorReporter
+		reported int
+	}{
+		{
+			name:     "writes",
+			handlers: []Handler{sendOK},
+			status:   http.StatusOK,
+			body:     ok,
+		},
+		{
+			name:     "writes then returns an internal error",
+			handlers: []Handler{sendOK, fail(LevelInternal, http.StatusInternalServerError, ErrInternal)},
+			status:   http.StatusOK,
+			body:     ok,
+			reported: 1,
+		},
+		{
+			name:     "writes then returns a client error",
+			handlers: []Handler{sendOK, fail(LevelClient, http.StatusBadRequest, ErrInvalidData)},
+			status:   http.StatusOK,
+			body:     ok,
+		},
+		{
+			name:     "returns a client error",
+			handlers: []Handler{fail(LevelClient, http.StatusBadRequest, ErrInvalidData), sendOK},
+			status:   http.StatusBadRequest,
+			body:     `{"error_code":"INVALID_DATA","status":"Bad Request","status_code":400}` + "\n",
+		},
+		{
+			name:     "returns a warning",
+			handlers: []Handler{fail(LevelWarn, http.StatusInternalServerError, ""), sendOK},
+			status:   http.StatusOK,
+			body:     ok,
+		},
+		{
+			name:     "returns nil without writing",
+			handlers: []Handler{doNothing},
+			status:   http.StatusInternalServerError,
+			body:     internal,
+			reported: 1,
+		},
+		{
+			name:     "panics before writing",
+			handlers: []Handler{panics, sendOK},
+			status:   http.StatusInternalServerError,
+			body:     internal,
+			reported: 1,
+		},
+		{
+			name:     "panics after writing",
+			handlers: []Handler{sendOK, panics},
+			status:   http.StatusOK,
+			body:     ok,
+			reported: 1,
+		},
+	}
+
+	defer func(r ErrorReporter) { reporter = r }(reporter)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rs reports
+			reporter = &rs
+
+			w := httptest.NewRecorder()
+			Handle(nil, time.Second, tt.handlers...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+			if len(rs) != tt.reported {
+				t.Errorf("%d errors reported, want %d", len(rs), tt.reported)
+			}
+		})
+	}
+}
+
+// countingDB counts requests the way db.DB does within a single window
+type countingDB struct {
+	stubDB
+	counts map[string]int64
+}
+
+func (d *countingDB) CountRequest(ctx context.Context, deviceid, action string, window time.Duration) (int64, error) {
+	d.counts[action+":"+deviceid]++
+	return d.counts[action+":"+deviceid], nil
+}
+
+// TestRateLimit checks that a device is refused once it's over the limit, Without affecting other devices
+func TestRateLimit(t *testing.T) {
+	defer func(l config.RateLimit) { rateLimits = l }(rateLimits)
+	rateLimits = config.RateLimit{Window: time.Minute}
+
+	d := &countingDB{counts: map[string]int64{}}
+	sendOK := func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		return Send(HTTPResponse(http.StatusOK), w)
+	}
+	h := Handle(d, time.Second, parseDeviceID(), rateLimit("posts", 2), sendOK)
+
+	request := func(deviceid string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("deviceid", deviceid)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, w.Code)
+		}
+	}
+
+	w := request("a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	if w := request("b"); w.Code != http.StatusOK {
+		t.Errorf("request of another device = %d, want 200", w.Code)
+	}
+}
+
+// TestUnmatched checks that requests matching no route are answered by Handle like the rest
+func TestUnmatched(t *testing.T) {
+	r := Init(stubDB{}, config.Default())
+
+	tests := []struct {
+		method, path string
+		status       int
+		errorCode    string
+	}{
+		{http.MethodGet, "/v1/nothing-here", http.StatusNotFound, ErrRouteNotFound},
+		{http.MethodDelete, "/v1/posts", http.StatusMethodNotAllowed, ErrMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+		if w.Header().Get("X-Request-ID") == "" {
+			t.Errorf("%s %s has no X-Request-ID", tt.method, tt.path)
+		}
+
+		e := HTTPError{}
+		if err := json.NewDecoder(w.Body).Decode(&e); err != nil || e.ErrorCode != tt.errorCode {
+			t.Errorf("%s %s sent error_code %q, want %q", tt.method, tt.path, e.ErrorCode, tt.errorCode)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ishanjain28/envelope-backend/db"
+)
+
+// route describes a single route of the API.
+// Routes are registered from this description and the OpenAPI document served at /openapi.json is generated from it,
+// So a route can't exist without being documented.
+type route struct {
+	method  string
+	path    string
+	summary string
+	// Longer explanation of the route, Optional
+	description string
+	// Group of the route in the documentation
+	tag    string
+	params []param
+	// Request body, A pointer to a request struct or nil if the route doesn't read a body
+	request interface{}
+	// Response sent on success and it's status code.
+	// In the v1 API, response is the data wrapped in an Envelope
+	status   int
+	response interface{}
+	// Content type of the response on success when it's not JSON, e.g. text/event-stream
+	produces string
+	// ErrorCodes that can be sent by the route, Besides ErrInternal and ErrTimeout that can be sent by any route
+	errors []string
+	// successor is the route in v1 API that replaces a deprecated legacy route
+	successor string
+	// Whether response is wrapped in an Envelope
+	enveloped bool
+	// Whether the response carries an ETag, 304 Not Modified is sent when If-None-Match has it
+	conditional bool
+	// Time the route has to respond in, defaultTimeout is used when it's 0 and noTimeout never times out
+	timeout  time.Duration
+	handlers []Handler
+}
+
+// noTimeout is the timeout of routes that stream responses for as long as the client is connected
+const noTimeout time.Duration = -1
+
+// param is a parameter of a route in it's path, query or headers
+type param struct {
+	name        string
+	in          string
+	description string
+	required    bool
+	// JSON schema type of the parameter
+	typ string
+}
+
+// Parameters used by multiple routes
+var (
+	deviceIDHeader = param{name: "deviceid", in: "header", description: "Unique Device ID", required: true, typ: "string"}
+	hashHeader     = param{name: "hash", in: "header", description: "Hash received on registering the device", required: true, typ: "string"}
+	postIDPath     = param{name: "id", in: "path", description: "ID of the post", required: true, typ: "integer"}
+	limitQuery     = param{name: "limit", in: "query", description: "Number of posts to fetch, 20 by default", typ: "integer"}
+
+	ifNoneMatchHeader = param{name: "If-None-Match", in: "header", description: "ETag of the response received before, 304 Not Modified is sent if it's still current", typ: "string"}
+	lastEventIDHeader = param{name: "Last-Event-ID", in: "header", description: "ID of the last event received, Events after it are sent first", typ: "string"}
+
+	// Errors sent by parseDeviceID and verifyDeviceID
+	authErrors = []string{ErrNotFound, ErrNotRegistered}
+	// Errors sent by decodeRequest
+	bodyErrors = []string{ErrParsing, ErrInvalidData, ErrTooLarge, ErrUnsupportedMediaType}
+)
+
+// legacyRoutes are routes mounted at the root. These are deprecated and kept for older clients until legacySunset,
+// Responses of these routes carry Deprecation and Sunset headers.
+func legacyRoutes() []route {
+	return []route{
+		{
+			method:    "POST",
+			path:      "/register-device",
+			summary:   "Register a Device",
+			tag:       "Device",
+			params:    []param{deviceIDHeader},
+			status:    200,
+			response:  RegisterDeviceResponse{},
+			errors:    []string{ErrNotFound, ErrOutOfValidRegion, ErrBanned},
+			successor: "/v1/devices",
+			handlers:  []Handler{parseDeviceID(), registerDevice()},
+		},
+		{
+			method:    "GET",
+			path:      "/verify-device",
+			summary:   "Verify a Device",
+			tag:       "Device",
+			params:    []param{deviceIDHeader, hashHeader},
+			status:    200,
+			response:  GenericResponse{},
+			errors:    []string{ErrNotFound, ErrNotRegistered, ErrExpired},
+			successor: "/v1/devices/me",
+			handlers:  []Handler{parseDeviceID(), verifyDevice()},
+		},
+		{
+			method:    "POST",
+			path:      "/report",
+			summary:   "Report a post",
+			tag:       "Post",
+			params:    []param{deviceIDHeader},
+			request:   &ReportRequest{},
+			status:    200,
+			response:  GenericResponse{},
+			errors:    append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			successor: "/v1/posts/{id}/reports",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("reports", rateLimits.Reports), report()},
+		},
+		{
+			method:    "POST",
+			path:      "/submit-post",
+			summary:   "Submit a post",
+			tag:       "Post",
+			params:    []param{deviceIDHeader},
+			request:   &SubmitPostRequest{},
+			status:    200,
+			response:  SubmitPostResponse{},
+			errors:    append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			successor: "/v1/posts",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("posts", rateLimits.Posts), submitPost()},
+		},
+		{
+			method:    "POST",
+			path:      "/edit-post",
+			summary:   "Edit a post",
+			tag:       "Post",
+			params:    []param{deviceIDHeader},
+			request:   &EditPostRequest{},
+			status:    200,
+			response:  SubmitPostResponse{},
+			errors:    append(append([]string{ErrNotOwner}, authErrors...), bodyErrors...),
+			successor: "/v1/posts/{id}",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), editPost()},
+		},
+		{
+			method:  "GET",
+			path:    "/fetch/{tag}",
+			summary: "Fetch latest posts, Or posts before or after a post",
+			tag:     "Post",
+			params: []param{
+				deviceIDHeader,
+				{name: "tag", in: "path", description: "latest, Or ID of the post to fetch posts before or after", required: true, typ: "string"},
+				{name: "prop", in: "query", description: "before or after, Required when tag is a postid", typ: "string"},
+				limitQuery,
+				ifNoneMatchHeader,
+			},
+			status:      200,
+			response:    []*db.Post{},
+			conditional: true,
+			errors:      append([]string{ErrInvalidData}, authErrors...),
+			successor:   "/v1/posts",
+			handlers:    []Handler{parseDeviceID(), verifyDeviceID(), fetchPost()},
+		},
+		{
+			method:    "POST",
+			path:      "/like-post",
+			summary:   "Like a post",
+			tag:       "Post",
+			params:    []param{deviceIDHeader},
+			request:   &LikePostRequest{},
+			status:    200,
+			response:  GenericResponse{},
+			errors:    append(append([]string{ErrAlreadyLiked}, authErrors...), bodyErrors...),
+			successor: "/v1/posts/{id}/likes",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), likePost()},
+		},
+		{
+			method:    "POST",
+			path:      "/comment",
+			summary:   "Comment on a post",
+			tag:       "Comment",
+			params:    []param{deviceIDHeader},
+			request:   &CommentRequest{},
+			status:    200,
+			response:  SubmitCommentResponse{},
+			errors:    append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			successor: "/v1/posts/{id}/comments",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("comments", rateLimits.Comments), submitComment()},
+		},
+		{
+			method:  "GET",
+			path:    "/fetch-comments",
+			summary: "Fetch comments on a post",
+			tag:     "Comment",
+			params: []param{
+				deviceIDHeader,
+				{name: "postid", in: "query", description: "ID of the post", required: true, typ: "integer"},
+			},
+			status:    200,
+			response:  []*db.Comment{},
+			errors:    append([]string{ErrInvalidData}, authErrors...),
+			successor: "/v1/posts/{id}/comments",
+			handlers:  []Handler{parseDeviceID(), verifyDeviceID(), fetchComments()},
+		},
+	}
+}
+
+// v1Routes are the routes of v1 API.
+// Every successful response is an Envelope and every error is an HTTPError.
+func v1Routes() []route {
+	routes := []route{
+		{
+			method:   "POST",
+			path:     "/v1/devices",
+			summary:  "Register a Device",
+			tag:      "Device",
+			params:   []param{deviceIDHeader},
+			status:   201,
+			response: DeviceResponse{},
+			errors:   []string{ErrNotFound, ErrOutOfValidRegion, ErrBanned},
+			handlers: []Handler{parseDeviceID(), v1RegisterDevice()},
+		},
+		{
+			method:   "GET",
+			path:     "/v1/devices/me",
+			summary:  "Verify a Device",
+			tag:      "Device",
+			params:   []param{deviceIDHeader, hashHeader},
+			status:   200,
+			errors:   []string{ErrNotFound, ErrNotRegistered, ErrExpired},
+			handlers: []Handler{parseDeviceID(), v1VerifyDevice()},
+		},
+		{
+			method:  "GET",
+			path:    "/v1/posts",
+			summary: "Fetch latest posts, Or posts before or after a post",
+			tag:     "Post",
+			params: []param{
+				deviceIDHeader,
+				{name: "before", in: "query", description: "Fetch posts older than this post", typ: "integer"},
+				{name: "after", in: "query", description: "Fetch this post and posts newer than it", typ: "integer"},
+				limitQuery,
+				ifNoneMatchHeader,
+			},
+			status:      200,
+			response:    []*db.Post{},
+			conditional: true,
+			errors:      append([]string{ErrInvalidData}, authErrors...),
+			handlers:    []Handler{parseDeviceID(), verifyDeviceID(), v1FetchPosts()},
+		},
+		{
+			method:   "POST",
+			path:     "/v1/posts",
+			summary:  "Submit a post",
+			tag:      "Post",
+			params:   []param{deviceIDHeader},
+			request:  &SubmitPostRequest{},
+			status:   201,
+			response: db.Post{},
+			errors:   append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("posts", rateLimits.Posts), v1SubmitPost()},
+		},
+		{
+			method:      "GET",
+			path:        "/v1/posts/{id}",
+			summary:     "Fetch a post along with it's comments",
+			tag:         "Post",
+			params:      []param{deviceIDHeader, postIDPath, ifNoneMatchHeader},
+			status:      200,
+			response:    db.Post{},
+			conditional: true,
+			errors:      append([]string{ErrInvalidData, ErrPostNotFound}, authErrors...),
+			handlers:    []Handler{parseDeviceID(), verifyDeviceID(), v1FetchPost()},
+		},
+		{
+			method:   "PATCH",
+			path:     "/v1/posts/{id}",
+			summary:  "Edit a post",
+			tag:      "Post",
+			params:   []param{deviceIDHeader, postIDPath},
+			request:  &UpdatePostRequest{},
+			status:   200,
+			response: db.Post{},
+			errors:   append(append([]string{ErrNotOwner}, authErrors...), bodyErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), v1EditPost()},
+		},
+		{
+			method:   "POST",
+			path:     "/v1/posts/{id}/likes",
+			summary:  "Like a post",
+			tag:      "Post",
+			params:   []param{deviceIDHeader, postIDPath},
+			status:   201,
+			response: LikesResponse{},
+			errors:   append([]string{ErrInvalidData, ErrAlreadyLiked}, authErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), v1LikePost()},
+		},
+		{
+			method:   "GET",
+			path:     "/v1/posts/{id}/comments",
+			summary:  "Fetch comments on a post",
+			tag:      "Comment",
+			params:   []param{deviceIDHeader, postIDPath},
+			status:   200,
+			response: []*db.Comment{},
+			errors:   append([]string{ErrInvalidData}, authErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), v1FetchComments()},
+		},
+		{
+			method:   "POST",
+			path:     "/v1/posts/{id}/comments",
+			summary:  "Comment on a post",
+			tag:      "Comment",
+			params:   []param{deviceIDHeader, postIDPath},
+			request:  &CreateCommentRequest{},
+			status:   201,
+			response: db.Comment{},
+			errors:   append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("comments", rateLimits.Comments), v1SubmitComment()},
+		},
+		{
+			method:   "POST",
+			path:     "/v1/posts/{id}/reports",
+			summary:  "Report a post",
+			tag:      "Post",
+			params:   []param{deviceIDHeader, postIDPath},
+			request:  &CreateReportRequest{},
+			status:   201,
+			errors:   append(append([]string{ErrRateLimited}, authErrors...), bodyErrors...),
+			handlers: []Handler{parseDeviceID(), verifyDeviceID(), rateLimit("reports", rateLimits.Reports), v1Report()},
+		},
+	}
+
+	for i := range routes {
+		routes[i].enveloped = true
+	}
+
+	// The stream switches to WebSocket protocol, It never sends an Envelope
+	routes = append(routes, route{
+		method:  "GET",
+		path:    "/v1/stream",
+		summary: "Receive changes in the feed over a WebSocket",
+		description: "Upgrades to a WebSocket connection and sends every change in the feed as a JSON text message " +
+			"{\"type\": ..., \"postid\": ..., \"data\": ...}. type is one of post.created, post.updated, post.deleted, like.count and comment.created. " +
+			"Clients that can't keep up are disconnected with close code 1013 (Try Again Later).",
+		tag:      "Stream",
+		params:   []param{deviceIDHeader, hashHeader},
+		status:   http.StatusSwitchingProtocols,
+		errors:   []string{ErrNotFound, ErrNotRegistered, ErrExpired},
+		timeout:  noTimeout,
+		handlers: []Handler{parseDeviceID(), v1Stream()},
+	})
+
+	// Server-Sent Events, For clients on networks that don't handle WebSockets well
+	sseDescription := "Sends the events of /v1/stream as Server-Sent Events, Along with a heartbeat comment every 15 seconds. " +
+		"A client reconnecting with Last-Event-ID receives the events it missed first, " +
+		"If they are no longer retained a reset event is sent and the client should refetch the feed."
+
+	sseErrors := []string{ErrInvalidData, ErrNotFound, ErrNotRegistered, ErrExpired}
+
+	routes = append(routes,
+		route{
+			method:      "GET",
+			path:        "/v1/events",
+			summary:     "Receive changes in the feed as Server-Sent Events",
+			description: sseDescription,
+			tag:         "Stream",
+			params:      []param{deviceIDHeader, hashHeader, lastEventIDHeader},
+			status:      http.StatusOK,
+			produces:    "text/event-stream",
+			errors:      sseErrors,
+			timeout:     noTimeout,
+			handlers:    []Handler{parseDeviceID(), v1Events()},
+		},
+		route{
+			method:      "GET",
+			path:        "/v1/posts/{id}/events",
+			summary:     "Receive changes in a post as Server-Sent Events",
+			description: sseDescription,
+			tag:         "Stream",
+			params:      []param{deviceIDHeader, hashHeader, postIDPath, lastEventIDHeader},
+			status:      http.StatusOK,
+			produces:    "text/event-stream",
+			errors:      sseErrors,
+			timeout:     noTimeout,
+			handlers:    []Handler{parseDeviceID(), v1Events()},
+		},
+	)
+
+	return routes
+}
+
+// metaRoutes serve information about the server itself
+func metaRoutes() []route {
+	return []route{
+		{
+			method:   "GET",
+			path:     "/openapi.json",
+			summary:  "OpenAPI document describing this API",
+			tag:      "Meta",
+			status:   200,
+			response: map[string]interface{}{},
+			handlers: []Handler{serveOpenAPI()},
+		},
+		{
+			method:  "GET",
+			path:    "/healthz",
+			summary: "Liveness of the server",
+			description: "Responds as long as the process can serve requests, Dependencies are not checked. " +
+				"A server failing this should be restarted.",
+			tag:      "Meta",
+			status:   200,
+			response: GenericResponse{},
+			handlers: []Handler{serveLiveness()},
+		},
+		{
+			method:  "GET",
+			path:    "/readyz",
+			summary: "Readiness of the server to receive traffic",
+			description: "Checks Postgres, Redis and that every migration has been applied, And reports the status of each. " +
+				"Responds with 503 when any of them fails or while the server is shutting down, Dependencies are not checked then.",
+			tag:      "Meta",
+			status:   200,
+			response: ReadinessResponse{},
+			timeout:  readinessTimeout,
+			handlers: []Handler{serveReadiness()},
+		},
+	}
+}
+
+// routes returns every route served by the application
+func routes() []route {
+	r := legacyRoutes()
+	r = append(r, v1Routes()...)
+	r = append(r, metaRoutes()...)
+	return r
+}
@@ -0,0 +1,160 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ishanjain28/envelope-backend/db"
+	"github.com/ishanjain28/envelope-backend/stream"
+)
+
+const (
+	// A comment is sent after this period, So proxies don't close an idle connection
+	sseHeartbeatPeriod = 15 * time.Second
+	// Time a client should wait before reconnecting, In milliseconds
+	sseRetry = 3000
+)
+
+// sseEvent is the part of an encoded stream.Event needed to frame and filter it
+type sseEvent struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	PostID int    `json:"postid"`
+}
+
+// v1Events streams the events sent on /v1/stream as Server-Sent Events, For networks that don't handle WebSockets well.
+// On /v1/posts/{id}/events only the events of post id are sent.
+//
+// A client reconnecting with Last-Event-ID receives the events it missed first. If they are no longer retained,
+// A reset event is sent and the client should refetch whatever it's showing.
+func v1Events() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		v := &validator{}
+
+		postid := 0
+		if id, ok := mux.Vars(r)["id"]; ok {
+			postid = v.integer("postid", id)
+		}
+
+		lastID := r.Header.Get("Last-Event-ID")
+		if lastID != "" && !stream.ValidID(lastID) {
+			v.add("Last-Event-ID", ErrInvalidData, "Last-Event-ID is not a valid event id")
+		}
+
+		if e := v.error(); e != nil {
+			return e
+		}
+
+		if e := rc.verify(r.Header.Get("hash")); e != nil {
+			return e
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return &HTTPError{
+				ErrorCode:       ErrInternal,
+				Level:           LevelInternal,
+				GenericResponse: HTTPResponse(http.StatusInternalServerError),
+				IError:          errors.New("response writer doesn't support flushing"),
+			}
+		}
+
+		// Each write gets streamWriteWait, The server's write timeout would end the stream otherwise.
+		// Where deadlines are not supported the server's timeout applies.
+		ctl := http.NewResponseController(w)
+		extendDeadline := func() {
+			ctl.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		}
+
+		// Subscribe before replaying, So no event is lost between the two
+		sub := events.Subscribe()
+		defer events.Unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Stops nginx from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		extendDeadline()
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+
+		if lastID != "" {
+			err := events.Replay(rc.ctx, lastID, func(id string, payload []byte) error {
+				lastID = id
+				extendDeadline()
+				return writeSSE(w, id, payload, postid)
+			})
+
+			if errors.Is(err, db.ErrEventsTrimmed) {
+				fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+			} else if err != nil {
+				rc.log().Warnf("error in replaying events: %s", err)
+				return nil
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(sseHeartbeatPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case payload, ok := <-sub.C:
+				if !ok {
+					// Client resumes from the last event it received when it reconnects
+					return nil
+				}
+
+				e := sseEvent{}
+				if err := json.Unmarshal(payload, &e); err != nil {
+					rc.log().Warnf("error in decoding event: %s", err)
+					continue
+				}
+
+				// Skip events that have already been sent while replaying
+				if lastID != "" && !stream.Before(lastID, e.ID) {
+					continue
+				}
+
+				extendDeadline()
+				if err := writeSSE(w, e.ID, payload, postid); err != nil {
+					return nil
+				}
+				flusher.Flush()
+
+			case <-ticker.C:
+				extendDeadline()
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+				flusher.Flush()
+
+			case <-rc.ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// writeSSE writes an encoded event to w as a Server-Sent Event with id.
+// Events of posts other than postid are skipped, Unless postid is 0.
+func writeSSE(w io.Writer, id string, payload []byte, postid int) error {
+	e := sseEvent{}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+
+	if postid != 0 && e.PostID != postid {
+		return nil
+	}
+
+	// Encoded events don't have newlines, So data fits in a single line
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, e.Type, payload)
+	return err
+}
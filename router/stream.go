@@ -0,0 +1,109 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ishanjain28/envelope-backend/stream"
+)
+
+const (
+	// Time allowed to write a message to the client
+	streamWriteWait = 10 * time.Second
+	// Time allowed to read the next pong from the client
+	streamPongWait = 60 * time.Second
+	// Pings are sent with this period, It must be less than streamPongWait
+	streamPingPeriod = 50 * time.Second
+	// Time allowed to publish an event after a change
+	publishTimeout = 2 * time.Second
+)
+
+// events delivers changes in the feed to clients connected to the stream, It's started by Init
+var events *stream.Hub
+
+// CloseStreams disconnects every client of /v1/stream and the Server-Sent Events routes, And stops receiving events.
+// http.Server doesn't wait for WebSockets and would wait for Server-Sent Events till it's deadline,
+// So this must be called when the server starts shutting down. Clients resume from the last event when they reconnect.
+func CloseStreams() error {
+	if events == nil {
+		return nil
+	}
+	return events.Close()
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// v1Stream upgrades the request to a WebSocket connection and pushes every event published on any instance to it.
+// The device must verify itself with deviceid and hash headers, Just like /v1/devices/me.
+// A client that can't keep up with the events is disconnected.
+func v1Stream() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		if e := rc.verify(r.Header.Get("hash")); e != nil {
+			return e
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already sent an error response to the client
+			return nil
+		}
+		defer conn.Close()
+
+		sub := events.Subscribe()
+		defer events.Unsubscribe(sub)
+
+		// Clients are not expected to send anything,
+		// Reading is still required to process pongs and the close message from client.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			conn.SetReadLimit(512)
+			conn.SetReadDeadline(time.Now().Add(streamPongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+			})
+
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(streamPingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case payload, ok := <-sub.C:
+				conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+
+				if !ok {
+					// Subscriber was dropped for being too slow, Or the server is shutting down
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscription closed"))
+					return nil
+				}
+
+				if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+					return nil
+				}
+
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return nil
+				}
+
+			case <-done:
+				return nil
+			}
+		}
+	}
+}
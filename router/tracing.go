@@ -0,0 +1,92 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/ishanjain28/envelope-backend/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Every request gets a span, It continues the trace of the caller when the request carries W3C trace context.
+// Each Handler in the chain gets a child span, Spans of SQL statements and Redis commands are children of those.
+
+// startRequestSpan starts the span of request r, It's named by the route template so names stay few
+func startRequestSpan(r *http.Request, route string) (trace.Span, *http.Request) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("http.request.method", r.Method),
+		attribute.String("http.route", route),
+	))
+
+	return span, r.WithContext(ctx)
+}
+
+// endRequestSpan records the response written to w and ends span.
+// Only responses sent because of internal errors mark the span as failed.
+func endRequestSpan(span trace.Span, w *responseWriter) {
+	span.SetAttributes(attribute.Int("http.response.status_code", w.status))
+	if w.errorCode != "" {
+		span.SetAttributes(attribute.String("error_code", w.errorCode))
+	}
+
+	if w.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(w.status))
+	}
+	span.End()
+}
+
+// runHandler runs h in a span named name.
+// The span is in rc.ctx while h runs, So spans created by h are it's children.
+func (rc *RouterContext) runHandler(name string, h Handler, w http.ResponseWriter, r *http.Request) *HTTPError {
+	parent := trace.SpanFromContext(rc.ctx)
+
+	ctx, span := tracing.Tracer().Start(rc.ctx, name)
+	defer span.End()
+
+	rc.ctx = ctx
+	e := h(rc, w, r)
+
+	// h may have replaced rc.ctx e.g. to add fields of the logger, Those are kept
+	rc.ctx = trace.ContextWithSpan(rc.ctx, parent)
+
+	if e != nil {
+		span.SetAttributes(attribute.String("error_level", e.Level.String()))
+		if e.ErrorCode != "" {
+			span.SetAttributes(attribute.String("error_code", e.ErrorCode))
+		}
+		if e.Level == LevelInternal {
+			span.SetStatus(codes.Error, fmt.Sprint(e.IError))
+		}
+	}
+
+	return e
+}
+
+// handlerName returns a name of h for it's span, e.g. router.v1FetchPosts for the Handler returned by v1FetchPosts()
+func handlerName(h Handler) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return "handler"
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	// Handlers are closures returned by functions, Their names end in .func1
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
@@ -1,63 +1,69 @@
 package router
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
-// parseForm parses the form in a request and handles the error appropriately
-func parseForm() Handler {
+// parseDeviceID parses "deviceid" from query parameters in a GET request and from Form value in a POST request
+func parseDeviceID() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
-		err := r.ParseForm()
 
-		if err != nil {
-			return &HTTPError{
-				IError:          err,
-				Level:           1,
-				GenericResponse: HTTPResponse(http.StatusBadRequest),
-				ErrorCode:       ErrParsing,
-			}
+		v := &validator{}
+
+		deviceid := r.Header.Get("deviceid")
+		v.required("deviceid", deviceid)
+
+		if e := v.error(); e != nil {
+			return e
 		}
+
+		rc.setDeviceID(deviceid)
 		return nil
 	}
 }
 
-// parseDeviceID parses "deviceid" from query parameters in a GET request and from Form value in a POST request
-func parseDeviceID() Handler {
+// verifyDeviceID is a middleware that can be plugged in to make sure the specified endpoint is only accessible to registered users
+func verifyDeviceID() Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		deviceid := r.Header.Get("deviceid")
-		if deviceid == "" {
-			return handleMissingDataError("deviceid")
+		_, err := rc.db.VerifyDeviceID(rc.ctx, rc.deviceid)
+		if err != nil {
+			return handleError(rc, err)
 		}
 
-		rc.deviceid = deviceid
 		return nil
 	}
 }
 
-// verifyDeviceID is a middleware that can be plugged in to make sure the specified endpoint is only accessible to registered users
-func verifyDeviceID() Handler {
+// rateLimit allows a device to make limit requests to do action in every window of rateLimits, Unlimited if limit is 0.
+// Requests are allowed when they can't be counted, Failing to count is logged as a warning.
+func rateLimit(action string, limit int) Handler {
 	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
 
-		_, err := rc.db.VerifyDeviceID(rc.ctx, rc.deviceid)
+		if limit == 0 {
+			return nil
+		}
 
+		n, err := rc.db.CountRequest(rc.ctx, rc.deviceid, action, rateLimits.Window)
 		if err != nil {
-			if err.Error() == ErrNotRegistered {
-				return &HTTPError{
-					ErrorCode:       ErrNotRegistered,
-					GenericResponse: HTTPResponse(http.StatusUnauthorized),
-					Level:           1,
-				}
+			return &HTTPError{
+				Level:  LevelWarn,
+				IError: fmt.Errorf("error in counting %s of the device, It's not limited: %s", action, err),
 			}
+		}
 
+		if n > int64(limit) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimits.Window.Seconds()))))
 			return &HTTPError{
-				deviceid:        rc.deviceid,
-				ErrorCode:       ErrInternal,
-				IError:          err,
-				Level:           3,
-				GenericResponse: HTTPResponse(http.StatusInternalServerError),
+				Level:           LevelClient,
+				IError:          fmt.Errorf("%s: device made %d %s in %s", ErrRateLimited, n, action, rateLimits.Window),
+				ErrorCode:       ErrRateLimited,
+				GenericResponse: HTTPResponse(http.StatusTooManyRequests),
 			}
 		}
 
@@ -65,24 +71,54 @@ func verifyDeviceID() Handler {
 	}
 }
 
+// unmatched answers requests that match no route with errorCode
+func unmatched(errorCode string) Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		return &HTTPError{
+			Level:           LevelClient,
+			IError:          fmt.Errorf("%s: %s %s", errorCode, r.Method, r.URL.Path),
+			ErrorCode:       errorCode,
+			GenericResponse: HTTPResponse(errorStatus[errorCode]),
+		}
+	}
+}
+
+// deprecated marks a legacy route as deprecated in favour of successor in v1 API.
+// Deprecation, Sunset and a Link to the successor are set on every response of the route.
+func deprecated(successor string) Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecation.Unix()))
+		w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+
+		return nil
+	}
+}
+
+// allowedRegion reports whether devices from region can register, Every region is allowed when regions is empty
+func allowedRegion(region string) bool {
+	if len(regions) == 0 {
+		return true
+	}
+
+	for _, r := range regions {
+		if strings.EqualFold(r, strings.TrimSpace(region)) {
+			return true
+		}
+	}
+	return false
+}
+
 func handleJSONError(err error) *HTTPError {
 	return &HTTPError{
 		ErrorCode:       ErrInternal,
 		IError:          err,
-		Level:           3,
+		Level:           LevelInternal,
 		GenericResponse: HTTPResponse(http.StatusInternalServerError),
 	}
 }
 
-// handleMissingDataError takes name of data that is missing or invalid and return *HTTPError
-func handleMissingDataError(v string) *HTTPError {
-	return &HTTPError{
-		Level:           1,
-		ErrorCode:       ErrNotFound,
-		GenericResponse: HTTPResponse(http.StatusBadRequest),
-	}
-}
-
 func fetchRemoteIpAddr(ip string) string {
 	if strings.Contains(ip, "[::1]") {
 		return "127.0.0.1"
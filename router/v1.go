@@ -0,0 +1,212 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ishanjain28/envelope-backend/db"
+)
+
+// Handlers of the v1 API, Routes are described in v1Routes
+
+// v1RegisterDevice registers deviceid and sends the hash it has to verify itself with
+func v1RegisterDevice() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		h, e := rc.register(r)
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusCreated, DeviceResponse{Hash: h})
+		return nil
+	}
+}
+
+// v1VerifyDevice verifies that deviceid is registered with the hash in request headers
+func v1VerifyDevice() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		if e := rc.verify(r.Header.Get("hash")); e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusOK, nil)
+		return nil
+	}
+}
+
+// v1FetchPosts sends a page of the feed.
+// Latest posts are sent by default, ?before=postid and ?after=postid page through older and newer posts.
+// 304 Not Modified is sent instead when If-None-Match has the current ETag of the page.
+func v1FetchPosts() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+		q := r.URL.Query()
+		v := &validator{}
+
+		limit := v.between("limit", q.Get("limit"), 1, maxFetchLimit, 20)
+
+		before, after := q.Get("before"), q.Get("after")
+
+		postid, prop := 0, ""
+		switch {
+		case before != "" && after != "":
+			v.add("after", ErrInvalidData, "after can't be used along with before")
+		case before != "":
+			postid, prop = v.integer("before", before), "before"
+		case after != "":
+			postid, prop = v.integer("after", after), "after"
+		}
+
+		if e := v.error(); e != nil {
+			return e
+		}
+
+		var (
+			posts []*db.Post
+			e     *HTTPError
+		)
+		if prop == "" {
+			posts, e = rc.latestPosts(limit)
+		} else {
+			posts, e = rc.postsFrom(postid, limit, prop)
+		}
+		if e != nil {
+			return e
+		}
+
+		if notModified(w, r, feedETag(posts)) {
+			return nil
+		}
+
+		SendData(w, http.StatusOK, posts)
+		return nil
+	}
+}
+
+// v1SubmitPost submits a new post
+func v1SubmitPost() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		req := &SubmitPostRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
+		}
+
+		p, e := rc.createPost(r, req.Post)
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusCreated, p)
+		return nil
+	}
+}
+
+// v1FetchPost sends a post along with all of it's comments, Or 304 Not Modified when If-None-Match has it's current ETag
+func v1FetchPost() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		p, e := rc.post(mux.Vars(r)["id"])
+		if e != nil {
+			return e
+		}
+
+		if notModified(w, r, postETag(p)) {
+			return nil
+		}
+
+		SendData(w, http.StatusOK, p)
+		return nil
+	}
+}
+
+// v1EditPost replaces text of a post
+func v1EditPost() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		req := &UpdatePostRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
+		}
+
+		p, e := rc.updatePost(mux.Vars(r)["id"], req.Post)
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusOK, p)
+		return nil
+	}
+}
+
+// v1LikePost likes a post and sends the total likes on it
+func v1LikePost() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		likes, e := rc.like(mux.Vars(r)["id"])
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusCreated, LikesResponse{LikesCount: likes})
+		return nil
+	}
+}
+
+// v1FetchComments sends all the comments on a post
+func v1FetchComments() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		c, e := rc.comments(mux.Vars(r)["id"])
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusOK, c)
+		return nil
+	}
+}
+
+// v1SubmitComment comments on a post
+func v1SubmitComment() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		req := &CreateCommentRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
+		}
+
+		c, e := rc.createComment(mux.Vars(r)["id"], req.Comment)
+		if e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusCreated, c)
+		return nil
+	}
+}
+
+// v1Report reports a post
+func v1Report() Handler {
+	return func(rc *RouterContext, w http.ResponseWriter, r *http.Request) *HTTPError {
+
+		req := &CreateReportRequest{}
+		if e := decodeRequest(w, r, req); e != nil {
+			return e
+		}
+
+		if e := rc.reportPost(mux.Vars(r)["id"], req.Reason); e != nil {
+			return e
+		}
+
+		SendData(w, http.StatusCreated, nil)
+		return nil
+	}
+}
+
+// SendData sends data wrapped in an Envelope with status code
+func SendData(w http.ResponseWriter, code int, data interface{}) *HTTPError {
+	w.WriteHeader(code)
+	return Send(Envelope{Data: data, GenericResponse: HTTPResponse(code)}, w)
+}
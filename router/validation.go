@@ -0,0 +1,103 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// FieldError describes a problem with a single field of a request
+type FieldError struct {
+	// Name of the field, As it is named in the request
+	Field string `json:"field"`
+	// Short Error Code, ErrNotFound when the field is missing and ErrInvalidData when it's value is invalid
+	Code string `json:"code"`
+	// Human readable description of the problem
+	Message string `json:"message"`
+}
+
+// validator accumulates problems with fields of a request,
+// So all of them can be reported to the client in a single response.
+type validator struct {
+	fields []FieldError
+}
+
+// add records a problem with field
+func (v *validator) add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// required records a problem if value is empty and reports whether it was present
+func (v *validator) required(field, value string) bool {
+	if value == "" {
+		v.add(field, ErrNotFound, fmt.Sprintf("%s is required", field))
+		return false
+	}
+	return true
+}
+
+// integer records a problem if value is missing or is not an integer
+func (v *validator) integer(field, value string) int {
+	if !v.required(field, value) {
+		return 0
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		v.add(field, ErrInvalidData, fmt.Sprintf("%s must be an integer", field))
+		return 0
+	}
+	return i
+}
+
+// between records a problem if value is present and is not an integer in [min, max].
+// def is returned when value is not present.
+func (v *validator) between(field, value string, min, max, def int) int {
+	if value == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < min || i > max {
+		v.add(field, ErrInvalidData, fmt.Sprintf("%s must be an integer between %d and %d", field, min, max))
+		return def
+	}
+	return i
+}
+
+// oneOf records a problem if value is missing or is not one of the options
+func (v *validator) oneOf(field, value string, options ...string) {
+	if !v.required(field, value) {
+		return
+	}
+
+	for _, o := range options {
+		if value == o {
+			return
+		}
+	}
+	v.add(field, ErrInvalidData, fmt.Sprintf("%s must be one of %q", field, options))
+}
+
+// maxLength records a problem if value is longer than n characters
+func (v *validator) maxLength(field, value string, n int) {
+	if utf8.RuneCountInString(value) > n {
+		v.add(field, ErrInvalidData, fmt.Sprintf("%s must be at most %d characters long", field, n))
+	}
+}
+
+// error returns a Level 1 *HTTPError containing all the recorded problems, Or nil if there are none.
+// ErrorCode of the response is the code of first problem.
+func (v *validator) error() *HTTPError {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return &HTTPError{
+		Level:           LevelClient,
+		ErrorCode:       v.fields[0].Code,
+		Fields:          v.fields,
+		GenericResponse: HTTPResponse(http.StatusBadRequest),
+	}
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseWriter keeps track of the response written by handlers, So Handle can make sure
+// every request gets exactly one response.
+// It supports flushing and hijacking when the underlying ResponseWriter does, Those are needed by the streams.
+type responseWriter struct {
+	http.ResponseWriter
+
+	// Status code sent to the client, 0 until the header is written
+	status int
+	// Whether the connection has been taken over by a handler, e.g. for a WebSocket
+	hijacked bool
+	// ErrorCode of the error response, If one was sent
+	errorCode string
+	// Number of bytes written in the body
+	bytes int64
+}
+
+// written reports whether a response has been sent, Nothing else must be written after it
+func (w *responseWriter) written() bool {
+	return w.status != 0 || w.hijacked
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written() {
+		return
+	}
+
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+		// The handler switches protocols on a hijacked connection, e.g. to WebSocket
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying ResponseWriter, It's used by http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
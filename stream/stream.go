@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ishanjain28/envelope-backend/log"
+)
+
+// Types of events sent to clients.
+// Posts can't be deleted through the API yet, post.deleted is published when posts are deleted by the ban command.
+const (
+	PostCreated    = "post.created"
+	PostUpdated    = "post.updated"
+	PostDeleted    = "post.deleted"
+	LikeCount      = "like.count"
+	CommentCreated = "comment.created"
+)
+
+// subscriberBuffer is the number of events that can be queued for a subscriber,
+// A subscriber that falls further behind is dropped.
+const subscriberBuffer = 64
+
+// Event is a change in the feed that is pushed to the connected clients
+type Event struct {
+	// ID of the event in the events log, Events are ordered by their IDs
+	ID     string      `json:"id,omitempty"`
+	Type   string      `json:"type"`
+	PostID int         `json:"postid"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// Broker delivers published events to every instance of the application and keeps a log of recent events.
+// It is implemented with Redis pub/sub and a Redis stream by db.DB.
+type Broker interface {
+	PublishEvent(ctx context.Context, payload []byte) error
+	// SubscribeEvents returns a channel receiving every published event and a function to stop the subscription
+	SubscribeEvents() (<-chan []byte, func() error)
+	// AppendEvent adds an event to the log and returns it's ID
+	AppendEvent(ctx context.Context, payload []byte) (string, error)
+	// EventsAfter calls fn with every event in the log after event id.
+	// An error is returned if id is not in the log anymore.
+	EventsAfter(ctx context.Context, id string, fn func(id string, payload []byte) error) error
+}
+
+// Subscriber receives encoded events from a Hub on C.
+// C is closed when the subscriber is dropped for being too slow or when the Hub is closed.
+type Subscriber struct {
+	C <-chan []byte
+
+	c chan []byte
+}
+
+// Hub fans out events received from the Broker to all the subscribers connected to this instance
+type Hub struct {
+	broker Broker
+
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+	stop        func() error
+}
+
+// NewHub returns a Hub that publishes and receives events through b
+func NewHub(b Broker) *Hub {
+	return &Hub{
+		broker:      b,
+		subscribers: map[*Subscriber]struct{}{},
+	}
+}
+
+// Run receives events from the Broker and sends them to subscribers until the Hub is closed
+func (h *Hub) Run() {
+	events, stop := h.broker.SubscribeEvents()
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		stop()
+		return
+	}
+	h.stop = stop
+	h.mu.Unlock()
+
+	for payload := range events {
+		h.broadcast(payload)
+	}
+}
+
+// Publish appends an event to the log and sends it to subscribers of every instance.
+// e.ID is set to ID of the event in the log.
+func (h *Hub) Publish(ctx context.Context, e *Event) error {
+	e.ID = ""
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	e.ID, err = h.broker.AppendEvent(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	// Subscribers receive the event along with it's ID, So they can resume from it
+	payload, err = json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return h.broker.PublishEvent(ctx, payload)
+}
+
+// Replay calls fn with every event published after event id that is still in the log.
+// Payloads passed to fn don't carry the ID, It's passed separately.
+func (h *Hub) Replay(ctx context.Context, id string, fn func(id string, payload []byte) error) error {
+	return h.broker.EventsAfter(ctx, id, fn)
+}
+
+// Subscribe returns a new Subscriber, That receives all the events published after this call
+func (h *Hub) Subscribe() *Subscriber {
+	c := make(chan []byte, subscriberBuffer)
+	s := &Subscriber{C: c, c: c}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(c)
+		return s
+	}
+
+	h.subscribers[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes s from the Hub and closes s.C, It's safe to call it on a dropped subscriber
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(s)
+}
+
+// Close stops receiving events and closes all the subscribers
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subscribers {
+		h.remove(s)
+	}
+
+	if h.stop != nil {
+		return h.stop()
+	}
+	return nil
+}
+
+// broadcast sends payload to all the subscribers without blocking.
+// Subscribers whose buffer is full are dropped, So one slow client can't hold back the others.
+func (h *Hub) broadcast(payload []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		select {
+		case s.c <- payload:
+		default:
+			log.Warnf("Dropping slow stream subscriber, %d events queued", len(s.c))
+			h.remove(s)
+		}
+	}
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(s *Subscriber) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+
+	delete(h.subscribers, s)
+	close(s.c)
+}
+
+// ValidID reports whether id is a valid event ID, That is <milliseconds>-<sequence>
+func ValidID(id string) bool {
+	_, _, ok := parseID(id)
+	return ok
+}
+
+// Before reports whether event a was published before event b.
+// Both IDs must be valid.
+func Before(a, b string) bool {
+	ams, aseq, _ := parseID(a)
+	bms, bseq, _ := parseID(b)
+
+	if ams != bms {
+		return ams < bms
+	}
+	return aseq < bseq
+}
+
+func parseID(id string) (uint64, uint64, bool) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return ms, seq, true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ishanjain28/envelope-backend/certs"
+	"github.com/ishanjain28/envelope-backend/config"
+)
+
+// tlsConfig returns configuration of HTTPS serving the certificate of r, HTTP/2 is preferred over HTTP/1.1
+func tlsConfig(r *certs.Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
+
+// hsts adds Strict-Transport-Security to every response of h, h is returned as is when max age is not set.
+// Browsers ignore it over plain HTTP, So it's safe to send behind a proxy that terminates TLS.
+func hsts(h http.Handler, c config.TLS) http.Handler {
+	if c.HSTSMaxAge <= 0 {
+		return h
+	}
+
+	value := fmt.Sprintf("max-age=%d", int64(c.HSTSMaxAge.Seconds()))
+	if c.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if c.HSTSPreload {
+		value += "; preload"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", value)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHTTPS redirects every request to the same URL over HTTPS on port.
+// GET and HEAD are redirected with 301, Everything else with 308 so the method and body are kept.
+func redirectToHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		// IPv6 addresses without a port are left in brackets by SplitHostPort
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+		if port != 443 {
+			host = net.JoinHostPort(host, fmt.Sprint(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
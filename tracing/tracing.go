@@ -0,0 +1,112 @@
+// Package tracing sets up OpenTelemetry tracing of requests.
+//
+// The exporter in configuration selects where spans are exported, Tracing is disabled when it's none.
+//   - otlp exports spans over OTLP/HTTP, It's configured with the standard $OTEL_EXPORTER_OTLP_* variables
+//     e.g. $OTEL_EXPORTER_OTLP_ENDPOINT, localhost:4318 by default.
+//   - stdout writes spans as JSON to stdout.
+//   - file writes spans as JSON to a file, So traces can be inspected in tests without a collector.
+//
+// Sample ratio is the fraction of new traces that are sampled.
+// Requests that carry a W3C traceparent header follow the sampling decision of the caller.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ishanjain28/envelope-backend/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of tracer of every span created by the application
+const instrumentationName = "github.com/ishanjain28/envelope-backend"
+
+var (
+	provider *sdktrace.TracerProvider
+	// file spans are written to by the file exporter, It's closed by Shutdown
+	file io.Closer
+)
+
+// Init sets up exporting of spans and propagation of W3C trace context.
+// Trace context is propagated even when exporting is disabled, So traces of callers aren't broken by us.
+func Init(c config.Tracing) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if c.Exporter == "" || c.Exporter == "none" {
+		return nil
+	}
+
+	exporter, err := newExporter(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(attribute.String("service.name", "envelope-backend")),
+		// $OTEL_SERVICE_NAME and $OTEL_RESOURCE_ATTRIBUTES override the defaults
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return nil
+}
+
+func newExporter(c config.Tracing) (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case "otlp":
+		return otlptracehttp.New(context.Background())
+
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+	case "file":
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	}
+
+	return nil, fmt.Errorf("unknown trace exporter %s, It must be otlp, stdout, file or none", c.Exporter)
+}
+
+// Tracer returns the tracer spans of the application are created with, It creates no spans when tracing is disabled
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Shutdown exports spans that have not been exported yet and stops exporting, ctx limits how long it waits.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+
+	err := provider.Shutdown(ctx)
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}